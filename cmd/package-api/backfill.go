@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+	"github.com/opengapps/package-api/pkg/github"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// backfillCommand adds the past releases from the commit history of the LATEST files, the stored ones are kept
+func backfillCommand(cfg *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("backfill", pflag.ExitOnError)
+	gitDir := flags.String("git", "", "Directory of the local <arch> clones of the platform repositories, Github API is used if it's empty")
+	archs := flags.StringSlice("arch", nil, "Platforms to backfill, all of them if it's empty")
+	dryRun := flags.Bool("dry-run", false, "Only report the missing releases without saving them")
+	reason := flags.String("reason", "", "Reason saved in the audit log")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	platforms := gapps.PlatformValues()
+	if len(*archs) > 0 {
+		platforms = nil
+		for _, arch := range *archs {
+			platform, err := gapps.PlatformString(arch)
+			if err != nil {
+				return fmt.Errorf("unable to parse arch '%s': %w", arch, err)
+			}
+			platforms = append(platforms, platform)
+		}
+	}
+
+	ctx := context.Background()
+	var history github.ReleaseHistory
+	var err error
+	if *gitDir != "" {
+		history, err = github.NewGitHistory(*gitDir)
+	} else {
+		history, err = github.NewGithubHistory(ctx, cfg.GetString(config.GithubTokenKey))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to init release history: %w", err)
+	}
+
+	var records []release.Record
+	for _, platform := range platforms {
+		platformRecords, err := github.HistoryRecords(ctx, history, platform)
+		if err != nil {
+			return err
+		}
+		log.WithField("arch", platform).WithField("count", len(platformRecords)).Info("Found the past releases")
+		records = append(records, platformRecords...)
+	}
+
+	releases, closeStorage, err := newRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	hook := audit.Hook(audit.Entry{Actor: cliActor, Action: release.SourceBackfill, Reason: *reason})
+	added, err := releases.Backfill(records, *dryRun, hook)
+	if err != nil {
+		return err
+	}
+	for _, key := range added {
+		log.WithField("dry_run", *dryRun).Infof("Added release '%s'", key)
+	}
+	log.WithField("added", len(added)).WithField("dry_run", *dryRun).Info("Backfilled the releases")
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// backupCommand saves the consistent snapshot of the storage to the file.
+// The checksum is saved next to it in the sha256sum format
+func backupCommand(cfg *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("backup", pflag.ExitOnError)
+	out := flags.StringP("out", "o", "", "Backup file path")
+	compress := flags.Bool("gzip", false, "Compress the backup with gzip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("backup file path is empty")
+	}
+
+	// the data is saved as is, without the migrations
+	storage, err := newStorage(cfg, db.WithoutMigrations())
+	if err != nil {
+		return fmt.Errorf("unable to init storage: %w", err)
+	}
+	defer func() {
+		if err = storage.Close(false); err != nil {
+			log.WithError(err).Error("Unable to close DB")
+		}
+	}()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return fmt.Errorf("unable to create backup file: %w", err)
+	}
+	h := sha256.New()
+	err = storage.Backup(func(s db.Snapshot) error {
+		return db.WriteSnapshot(io.MultiWriter(f, h), s, *compress)
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*out)
+		return fmt.Errorf("unable to save backup: %w", err)
+	}
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+	line := fmt.Sprintf("%s  %s\n", checksum, filepath.Base(*out))
+	if err = os.WriteFile(*out+".sha256", []byte(line), 0644); err != nil {
+		return fmt.Errorf("unable to save backup checksum: %w", err)
+	}
+	log.WithField("checksum", checksum).Infof("Saved the backup to '%s'", *out)
+	return nil
+}
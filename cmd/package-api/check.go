@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/release"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// dbCommands lists the subcommands of the db command
+var dbCommands = map[string]func(cfg *viper.Viper, args []string) error{
+	"check": checkCommand,
+}
+
+// dbCommand runs the DB maintenance subcommand
+func dbCommand(cfg *viper.Viper, args []string) error {
+	if len(args) == 0 {
+		return errors.New("subcommand is required")
+	}
+	cmd, ok := dbCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown subcommand '%s'", args[0])
+	}
+	return cmd(cfg, args[1:])
+}
+
+// checkCommand validates the stored releases, optionally repairing or quarantining the bad ones.
+// It fails if any problem is left in the DB
+func checkCommand(cfg *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("check", pflag.ExitOnError)
+	quarantine := flags.Bool("quarantine", false, "Move the bad values to the quarantine bucket")
+	repair := flags.Bool("repair", false, "Repair the bad values if possible, quarantine the rest")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	mode := release.CheckReport
+	switch {
+	case *repair:
+		mode = release.CheckRepair
+	case *quarantine:
+		mode = release.CheckQuarantine
+	}
+
+	releases, closeStorage, err := newRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	result, err := releases.Check(mode, audit.Hook(audit.Entry{Actor: cliActor, Action: string(mode)}))
+	if err != nil {
+		return err
+	}
+	for _, problem := range result.Problems {
+		log.WithField("action", problem.Action).Warnf("Bad value for key '%s': %s", problem.Key, problem.Reason)
+	}
+	log.WithField("checked", result.Checked).WithField("problems", len(result.Problems)).Info("Checked the DB")
+	if mode == release.CheckReport && len(result.Problems) > 0 {
+		return fmt.Errorf("found %d bad values", len(result.Problems))
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// commands lists the maintenance commands, which are run instead of the service
+var commands = map[string]func(cfg *viper.Viper, args []string) error{
+	"backup":   backupCommand,
+	"export":   exportCommand,
+	"import":   importCommand,
+	"compact":  compactCommand,
+	"db":       dbCommand,
+	"backfill": backfillCommand,
+}
+
+// runCommand runs the maintenance command with its own arguments
+func runCommand(cfg *viper.Viper, name string, args []string) {
+	cmd, ok := commands[name]
+	if !ok {
+		log.Fatalf("Unknown command '%s'", name)
+	}
+	if err := cmd(cfg, args); err != nil {
+		log.WithError(err).Fatalf("Unable to run '%s'", name)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/release"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// retentionActor is the name of the retention job in the audit log
+const retentionActor = "retention"
+
+// retentionPolicy returns the retention policy from the config
+func retentionPolicy(cfg *viper.Viper) (release.Policy, error) {
+	policy := release.Policy{
+		KeepLast:  cfg.GetInt(config.RetentionKeepLastKey),
+		KeepAfter: cfg.GetString(config.RetentionKeepAfterKey),
+	}
+	return policy, policy.Validate()
+}
+
+// compactCommand applies the retention policy and reclaims the space left by the removed releases
+func compactCommand(cfg *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("compact", pflag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "Only report the releases which would be removed")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	policy, err := retentionPolicy(cfg)
+	if err != nil {
+		return err
+	}
+
+	storage, err := newStorage(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to init storage: %w", err)
+	}
+	defer func() {
+		if err = storage.Close(false); err != nil {
+			log.WithError(err).Error("Unable to close DB")
+		}
+	}()
+	releases, err := release.NewRepository(storage)
+	if err != nil {
+		return err
+	}
+
+	hook := audit.Hook(audit.Entry{Actor: cliActor, Action: retentionActor})
+	removed, err := releases.ApplyRetention(policy, *dryRun, hook)
+	if err != nil {
+		return err
+	}
+	for _, key := range removed {
+		log.WithField("dry_run", *dryRun).Infof("Removed key '%s'", key)
+	}
+	if *dryRun {
+		return nil
+	}
+
+	path := cfg.GetString(config.DBPathKey)
+	before := fileSize(path)
+	if err = storage.Compact(); err != nil {
+		return err
+	}
+	log.WithField("before", before).WithField("after", fileSize(path)).Info("Compacted the DB")
+	return nil
+}
+
+// fileSize returns the size of the DB file, or 0 if there is no such file
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
@@ -4,6 +4,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,18 +13,32 @@ import (
 	packageapi "github.com/opengapps/package-api/internal/app/package-api"
 	"github.com/opengapps/package-api/internal/pkg/config"
 	"github.com/opengapps/package-api/internal/pkg/db"
-	"github.com/opengapps/package-api/pkg/github"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/internal/pkg/replication"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 )
 
-var configName string
+var (
+	configName  string
+	migrateOnly bool
+	dryRun      bool
+	modeFlag    string
+	once        bool
+)
 
 func init() {
 	// get flags, init logger
 	pflag.StringVarP(&configName, "config", "c", app.Name, "Config file name")
 	level := pflag.String("log-level", "INFO", "Logrus log level (DEBUG, WARN, etc.)")
+	pflag.BoolVar(&migrateOnly, "migrate-only", false, "Apply the pending DB migrations and exit")
+	pflag.BoolVar(&dryRun, "dry-run", false, "Only report the pending DB migrations without saving them (with --migrate-only)")
+	pflag.StringVar(&modeFlag, "mode", "", "Run mode (api, watcher, all), overrides the mode config key")
+	pflag.BoolVar(&once, "once", false, "Run the watcher jobs once and exit")
+	// the flags after the command name belong to the command
+	pflag.CommandLine.SetInterspersed(false)
 	pflag.Parse()
 
 	logLevel, err := log.ParseLevel(*level)
@@ -34,6 +49,10 @@ func init() {
 	}
 	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
 	log.SetOutput(os.Stdout)
+	if pflag.NArg() > 0 {
+		// the commands may use stdout for the data
+		log.SetOutput(os.Stderr)
+	}
 	log.SetLevel(logLevel)
 	log.Debug("Enabling debug logging")
 
@@ -54,33 +73,87 @@ func main() {
 		log.WithError(err).Fatal("Unable to init config")
 	}
 
+	if migrateOnly {
+		migrateStorage(cfg)
+		return
+	}
+	if pflag.NArg() > 0 {
+		runCommand(cfg, pflag.Arg(0), pflag.Args()[1:])
+		return
+	}
+	mode, err := runMode(cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Unable to init run mode")
+	}
+	if once && mode == modeAPI {
+		log.Fatal("The watcher jobs can't be run in the api mode")
+	}
+
 	// init storage
 	log.Debug("Initiating DB")
-	storage, err := db.New(cfg.GetString(config.DBPathKey), cfg.GetDuration(config.DBTimeoutKey))
+	storage, err := newStorage(cfg)
 	if err != nil {
 		log.WithError(err).Fatal("Unable to init storage")
 	}
 
-	// init Github client
-	log.Debug("Creating Github client")
-	githubClient, err := github.NewClient(
-		ctx,
-		github.WithConfig(cfg),
-		github.WithStorage(storage),
-	)
+	releases, err := release.NewRepository(storage)
 	if err != nil {
-		log.WithError(err).Fatal("Unable to init Github client")
+		log.WithError(err).Fatal("Unable to init release repository")
 	}
-	go githubClient.Watch(ctx)
 
-	// create the server
-	log.Debug("Creating the app server")
-	a, err := packageapi.New(
+	replica, isReplica := storage.(*db.Replica)
+	if !isReplica {
+		// the followers need the position in the change log to request the next changes since
+		n, err := releases.SeedChanges()
+		if err != nil {
+			log.WithError(err).Fatal("Unable to seed the change log")
+		}
+		if n > 0 {
+			log.WithField("releases", n).Info("Added the releases to the empty change log")
+		}
+	}
+
+	leader := cfg.GetString(config.ReplicationLeaderKey)
+	if (isReplica || leader != "") && (once || mode == modeWatcher) {
+		log.Fatal("The watcher jobs need the writable DB, which is not the replica or the follower")
+	}
+	if once {
+		err = runOnce(ctx, cfg, storage, releases)
+		if closeErr := storage.Close(false); closeErr != nil {
+			log.WithError(closeErr).Error("Unable to close DB")
+		}
+		if err != nil {
+			log.WithError(err).Fatal("Unable to run the watcher jobs")
+		}
+		log.Info("Finished the watcher jobs")
+		return
+	}
+
+	appOpts := []packageapi.Option{
 		packageapi.WithConfig(cfg),
 		packageapi.WithStorage(storage),
-	)
-	if err != nil {
-		log.WithError(err).Fatal("Unable to init application")
+		packageapi.WithRepository(releases),
+	}
+	switch {
+	case isReplica:
+		// the replica only follows the snapshot of the writer
+		log.Warn("Running as the read-only replica")
+		go replica.Watch(ctx, cfg.GetDuration(config.ReplicaIntervalKey))
+		appOpts = append(appOpts, packageapi.WithReadOnly())
+	case leader != "":
+		// the follower only applies the changes of the leader
+		log.WithField("leader", leader).Warn("Running as the replication follower")
+		follower, err := replication.NewFollower(
+			replication.WithLeader(leader, cfg.GetString(config.ReplicationKeyKey)),
+			replication.WithRepository(releases),
+		)
+		if err != nil {
+			log.WithError(err).Fatal("Unable to init replication follower")
+		}
+		go follower.Watch(ctx, cfg.GetDuration(config.ReplicationIntervalKey))
+		appOpts = append(appOpts, packageapi.WithReadOnly(), packageapi.WithFollower(follower))
+	case mode != modeAPI:
+		appOpts = append(appOpts, packageapi.WithChecker(runWriter(ctx, cfg, storage, releases)))
 	}
 
 	// init graceful stop chan
@@ -89,6 +162,25 @@ func main() {
 	signal.Notify(gracefulStop, syscall.SIGTERM)
 	signal.Notify(gracefulStop, syscall.SIGINT)
 
+	if mode == modeWatcher {
+		log.Info("Running the watcher only")
+		sig := <-gracefulStop
+		log.Warnf("Caught sig %+v, stopping the watcher", sig)
+		cancel()
+		if err = storage.Close(false); err != nil {
+			log.WithError(err).Error("Unable to close DB")
+		}
+		log.Info("Shutting down")
+		return
+	}
+
+	// create the server
+	log.Debug("Creating the app server")
+	a, err := packageapi.New(appOpts...)
+	if err != nil {
+		log.WithError(err).Fatal("Unable to init application")
+	}
+
 	go func() {
 		sig := <-gracefulStop
 		log.Warnf("Caught sig %+v, stopping the app", sig)
@@ -108,3 +200,76 @@ func main() {
 		log.WithError(err).Fatal("Unable to start the server")
 	}
 }
+
+// newStorage creates the storage selected by the config
+func newStorage(cfg *viper.Viper, opts ...db.Option) (db.Storage, error) {
+	if cfg.GetBool(config.ReplicaEnabledKey) {
+		if cfg.GetBool(config.DBCacheKey) {
+			log.Warn("DB cache is not used by the replica, as the snapshot is changed by the writer")
+		}
+		return newReplica(cfg)
+	}
+
+	driver := cfg.GetString(config.DBDriverKey)
+	path := cfg.GetString(config.DBPathKey)
+	timeout := cfg.GetDuration(config.DBTimeoutKey)
+
+	var storage db.Storage
+	switch driver {
+	case db.DriverSQLite:
+		s, err := db.NewSQLite(path, timeout, opts...)
+		if err != nil {
+			return nil, err
+		}
+		storage = s
+	case db.DriverBolt:
+		if path == db.MemoryPath {
+			log.Warn("Using in-memory DB, the data will be lost on shutdown")
+			storage = db.NewMemory()
+			break
+		}
+		s, err := db.New(path, timeout, opts...)
+		if err != nil {
+			return nil, err
+		}
+		storage = s
+	default:
+		return nil, fmt.Errorf("unknown DB driver '%s'", driver)
+	}
+
+	if cfg.GetBool(config.DBCacheKey) {
+		storage = db.NewCache(storage)
+	}
+	return storage, nil
+}
+
+// migrateStorage applies or reports the pending DB migrations
+func migrateStorage(cfg *viper.Viper) {
+	storage, err := newStorage(cfg, db.WithoutMigrations())
+	if err != nil {
+		log.WithError(err).Fatal("Unable to init storage")
+	}
+	defer func() {
+		if err = storage.Close(false); err != nil {
+			log.WithError(err).Error("Unable to close DB")
+		}
+	}()
+
+	reports, err := storage.Migrate(dryRun)
+	if err != nil {
+		// the storage is closed before the exit, so the deploy sees the failure
+		if closeErr := storage.Close(false); closeErr != nil {
+			log.WithError(closeErr).Error("Unable to close DB")
+		}
+		log.WithError(err).Fatal("Unable to migrate DB")
+	}
+	if len(reports) == 0 {
+		log.Infof("DB schema version %d is up to date", db.SchemaVersion())
+		return
+	}
+	for _, r := range reports {
+		for _, key := range r.Changes {
+			log.WithField("version", r.Version).WithField("dry_run", dryRun).Infof("Changed key '%s'", key)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	packageapi "github.com/opengapps/package-api/internal/app/package-api"
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/github"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Run modes
+const (
+	// modeAPI serves HTTP only
+	modeAPI = "api"
+	// modeWatcher runs the background jobs changing the releases only
+	modeWatcher = "watcher"
+	// modeAll runs both
+	modeAll = "all"
+)
+
+// runMode returns the run mode from the flag, or from the config if the flag is not set
+func runMode(cfg *viper.Viper) (string, error) {
+	mode := modeFlag
+	if mode == "" {
+		mode = cfg.GetString(config.ModeKey)
+	}
+	switch mode {
+	case modeAPI, modeWatcher, modeAll:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown run mode '%s'", mode)
+}
+
+// runWriter starts the background jobs changing the releases, returning the release checker for the webhook
+func runWriter(ctx context.Context, cfg *viper.Viper, storage db.Storage, releases *release.Repository) packageapi.Checker {
+	// init Github client
+	log.Debug("Creating Github client")
+	githubClient, err := github.NewClient(
+		ctx,
+		github.WithConfig(cfg),
+		github.WithRepository(releases),
+	)
+	if err != nil {
+		log.WithError(err).Fatal("Unable to init Github client")
+	}
+	go githubClient.Watch(ctx)
+
+	// remove the old releases in background
+	policy, err := retentionPolicy(cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Unable to init retention policy")
+	}
+	if policy.Enabled() {
+		hook := audit.Hook(audit.Entry{Actor: retentionActor, Action: retentionActor})
+		go releases.WatchRetention(ctx, policy, cfg.GetDuration(config.RetentionIntervalKey), hook)
+	}
+
+	// feed the replicas
+	if path := cfg.GetString(config.ReplicaSnapshotKey); path != "" {
+		go publishSnapshots(ctx, storage, path, cfg.GetDuration(config.ReplicaIntervalKey))
+	}
+	return githubClient
+}
+
+// runOnce runs every background job changing the releases once
+func runOnce(ctx context.Context, cfg *viper.Viper, storage db.Storage, releases *release.Repository) error {
+	githubClient, err := github.NewClient(
+		ctx,
+		github.WithConfig(cfg),
+		github.WithRepository(releases),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to init Github client: %w", err)
+	}
+	// the other jobs run even if some of the platforms failed
+	summary := githubClient.Check(ctx)
+	summary.Log()
+	checkErr := summary.Err()
+
+	policy, err := retentionPolicy(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to init retention policy: %w", err)
+	}
+	hook := audit.Hook(audit.Entry{Actor: retentionActor, Action: retentionActor})
+	removed, err := releases.ApplyRetention(policy, false, hook)
+	if err != nil {
+		return fmt.Errorf("unable to apply the retention policy: %w", err)
+	}
+	if len(removed) > 0 {
+		log.WithField("keys", removed).Info("Removed the old releases")
+	}
+
+	if path := cfg.GetString(config.ReplicaSnapshotKey); path != "" {
+		if err = db.PublishSnapshot(storage, path); err != nil {
+			return err
+		}
+	}
+	return checkErr
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/db"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// newReplica creates the read-only storage serving the snapshot published by the writer
+func newReplica(cfg *viper.Viper) (*db.Replica, error) {
+	path := cfg.GetString(config.ReplicaSnapshotKey)
+	if path == "" {
+		return nil, errors.New("replica snapshot path is not set")
+	}
+	if driver := cfg.GetString(config.DBDriverKey); driver != db.DriverBolt {
+		return nil, errors.New("replica supports only the bolt DB driver")
+	}
+	return db.NewReplica(path, cfg.GetDuration(config.DBTimeoutKey))
+}
+
+// publishSnapshots writes the storage snapshot for the replicas with the interval until the context is canceled
+func publishSnapshots(ctx context.Context, storage db.Storage, path string, interval time.Duration) {
+	publish := func() {
+		if err := db.PublishSnapshot(storage, path); err != nil {
+			log.WithError(err).Error("Unable to publish the DB snapshot")
+			return
+		}
+		log.WithField("path", path).Debug("Published the DB snapshot")
+	}
+
+	publish()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Context canceled, exiting snapshot publisher")
+			return
+		case <-ticker.C:
+			publish()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/release"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// stdio selects the standard input or output instead of the file
+	stdio = "-"
+	// cliActor is the name of the command line user in the audit log
+	cliActor = "cli"
+)
+
+// exportCommand writes all of the releases as newline-delimited JSON
+func exportCommand(cfg *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("export", pflag.ExitOnError)
+	out := flags.StringP("out", "o", stdio, "Export file path, '-' for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	releases, closeStorage, err := newRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	// stdout is never closed, so the later output is not lost
+	var (
+		w    io.Writer = os.Stdout
+		file *os.File
+	)
+	if *out != stdio {
+		if file, err = os.Create(*out); err != nil {
+			return fmt.Errorf("unable to create export file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := releases.Export(w)
+	if err != nil {
+		return err
+	}
+	if file != nil {
+		if err = file.Close(); err != nil {
+			return fmt.Errorf("unable to close export file: %w", err)
+		}
+	}
+	log.WithField("count", count).Info("Exported the records")
+	return nil
+}
+
+// importCommand loads the releases from newline-delimited JSON
+func importCommand(cfg *viper.Viper, args []string) error {
+	flags := pflag.NewFlagSet("import", pflag.ExitOnError)
+	in := flags.StringP("in", "i", stdio, "Import file path, '-' for stdin")
+	modeName := flags.String("mode", string(release.ImportMerge), "Conflict mode (merge, overwrite, skip)")
+	reason := flags.String("reason", "", "Reason saved in the audit log")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	mode, err := release.ParseImportMode(*modeName)
+	if err != nil {
+		return err
+	}
+
+	releases, closeStorage, err := newRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	var r io.Reader = os.Stdin
+	if *in != stdio {
+		f, err := os.Open(*in)
+		if err != nil {
+			return fmt.Errorf("unable to open import file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	entry := audit.Entry{Actor: cliActor, Action: "import", Reason: *reason}
+	result, err := releases.Import(r, mode, audit.Hook(entry))
+	if err != nil {
+		return err
+	}
+	log.WithField("added", result.Added).
+		WithField("updated", result.Updated).
+		WithField("skipped", result.Skipped).
+		Info("Imported the records")
+	return nil
+}
+
+// newRepository creates the release repository with its own storage, which is closed by the returned function
+func newRepository(cfg *viper.Viper) (*release.Repository, func(), error) {
+	storage, err := newStorage(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to init storage: %w", err)
+	}
+	closeStorage := func() {
+		if err := storage.Close(false); err != nil {
+			log.WithError(err).Error("Unable to close DB")
+		}
+	}
+
+	releases, err := release.NewRepository(storage)
+	if err != nil {
+		closeStorage()
+		return nil, nil, err
+	}
+	return releases, closeStorage, nil
+}
@@ -7,7 +7,11 @@ import (
 	"net/http"
 
 	"github.com/opengapps/package-api/internal/app"
+	"github.com/opengapps/package-api/internal/pkg/audit"
 	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/internal/pkg/replication"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -21,12 +25,24 @@ const (
 	queryArgAPI     = "api"
 	queryArgVariant = "variant"
 	queryArgDate    = "date"
+	queryArgGzip    = "gzip"
+	queryArgMode    = "mode"
+	queryArgReason  = "reason"
+	queryArgFrom    = "from"
+	queryArgTo      = "to"
+	queryArgAction  = "action"
+	queryArgAsOf    = "as_of"
 )
 
 type application struct {
-	cfg     *viper.Viper
-	server  *http.Server
-	storage Storage
+	cfg      *viper.Viper
+	server   *http.Server
+	storage  db.Storage
+	releases *release.Repository
+	audit    *audit.Log
+	follower *replication.Follower
+	checker  Checker
+	readOnly bool
 }
 
 // New creates new instance of Application
@@ -43,6 +59,14 @@ func New(opts ...Option) (*application, error) {
 	if a.storage == nil {
 		return nil, errors.New("passed storage is nil")
 	}
+	if a.releases == nil {
+		return nil, errors.New("passed repository is nil")
+	}
+	auditLog, err := audit.New(a.storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create audit log: %w", err)
+	}
+	a.audit = auditLog
 
 	a.server = &http.Server{
 		Addr:         a.cfg.GetString(config.ServerHostKey) + ":" + a.cfg.GetString(config.ServerPortKey),
@@ -72,11 +96,64 @@ func (a *application) Run() error {
 	r.Name("rss").Path(a.cfg.GetString(config.RSSEndpointKey)).
 		Methods(http.MethodGet).
 		HandlerFunc(a.rssHandler())
+	r.Name("status").Path(a.cfg.GetString(config.StatusEndpointKey)).
+		Methods(http.MethodGet).
+		HandlerFunc(a.statusHandler())
 
 	// set auth-covered handlers
-	r.Name("pkg").Path(a.cfg.GetString(config.PkgEndpointKey)).
-		Methods(http.MethodPost).
-		Handler(authMiddleware(a.cfg.GetString(config.AuthKey), a.pkgHandler()))
+	authKeys := a.authKeys()
+	// the history holds the disabled and removed releases, which are not served by the list
+	r.Name("history").Path(a.cfg.GetString(config.HistoryEndpointKey)).
+		Methods(http.MethodGet).
+		Queries(queryArgArch, "", queryArgDate, "").
+		Handler(authMiddleware(authKeys, a.historyHandler()))
+	r.Name("pkg-state").Path(a.cfg.GetString(config.PkgEndpointKey)).
+		Methods(http.MethodGet).
+		Queries(queryArgDate, "").
+		Handler(authMiddleware(authKeys, a.pkgStateHandler()))
+	r.Name("backup").Path(a.cfg.GetString(config.BackupEndpointKey)).
+		Methods(http.MethodGet).
+		Handler(authMiddleware(authKeys, a.backupHandler()))
+	r.Name("export").Path(a.cfg.GetString(config.ExportEndpointKey)).
+		Methods(http.MethodGet).
+		Handler(authMiddleware(authKeys, a.exportHandler()))
+	r.Name("audit").Path(a.cfg.GetString(config.AuditEndpointKey)).
+		Methods(http.MethodGet).
+		Handler(authMiddleware(authKeys, a.auditHandler()))
+	r.Name("check").Path(a.cfg.GetString(config.CheckEndpointKey)).
+		Methods(http.MethodGet).
+		Handler(authMiddleware(authKeys, a.checkHandler()))
+	r.Name("changes").Path(a.cfg.GetString(config.ChangesEndpointKey)).
+		Methods(http.MethodGet).
+		Handler(authMiddleware(authKeys, replication.NewHandler(a.releases)))
+
+	// set the writers, the replicas and the followers serve the data of the other process
+	if a.readOnly {
+		log.Warn("Serving the read-only data, the admin changes are disabled")
+	} else {
+		r.Name("pkg").Path(a.cfg.GetString(config.PkgEndpointKey)).
+			Methods(http.MethodPost).
+			Handler(authMiddleware(authKeys, a.pkgHandler()))
+		r.Name("import").Path(a.cfg.GetString(config.ImportEndpointKey)).
+			Methods(http.MethodPost).
+			Handler(authMiddleware(authKeys, a.importHandler()))
+		r.Name("check-fix").Path(a.cfg.GetString(config.CheckEndpointKey)).
+			Methods(http.MethodPost).
+			Handler(authMiddleware(authKeys, a.checkHandler()))
+	}
+
+	// set the release checks, they are run by the watcher of the same process
+	if a.checker != nil {
+		r.Name("refresh").Path(a.cfg.GetString(config.RefreshEndpointKey)).
+			Methods(http.MethodPost).
+			Handler(authMiddleware(authKeys, a.refreshHandler()))
+	}
+	// the webhook is signed by GitHub instead of the auth key
+	if a.checker != nil && a.cfg.GetString(config.GithubWebhookSecretKey) != "" {
+		r.Name("webhook").Path(a.cfg.GetString(config.WebhookEndpointKey)).
+			Methods(http.MethodPost).
+			HandlerFunc(a.webhookHandler())
+	}
 
 	// set handler with middlewares
 	a.server.Handler = withMiddlewares(r)
@@ -86,6 +163,16 @@ func (a *application) Run() error {
 	return a.server.ListenAndServe()
 }
 
+// authKeys returns the API keys by the names of their actors
+func (a *application) authKeys() map[string]string {
+	keys := make(map[string]string)
+	for actor, key := range a.cfg.GetStringMapString(config.AuthKeysKey) {
+		keys[actor] = key
+	}
+	keys[defaultActor] = a.cfg.GetString(config.AuthKey)
+	return keys
+}
+
 // Close stops the Application
 func (a *application) Close() error {
 	return a.server.Shutdown(context.Background())
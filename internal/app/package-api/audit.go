@@ -0,0 +1,45 @@
+package packageapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+)
+
+type auditResponse struct {
+	Entries []audit.Entry `json:"entries,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *auditResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+func (a *application) auditHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &auditResponse{}
+		query := r.URL.Query()
+		filter := audit.Filter{
+			From:   query.Get(queryArgFrom),
+			To:     query.Get(queryArgTo),
+			Action: query.Get(queryArgAction),
+		}
+		if err := filter.Validate(); err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+			return
+		}
+
+		entries, err := a.audit.List(filter)
+		if err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
+			return
+		}
+		resp.Entries = entries
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
@@ -0,0 +1,68 @@
+package packageapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ChecksumHeader holds the hex-encoded SHA-256 of the backup body, it's sent as the trailer
+	ChecksumHeader = "X-Checksum-Sha256"
+
+	backupFileTemplate = "package-api-%s.db"
+	backupTimeFormat   = "20060102T150405Z"
+)
+
+func (a *application) backupHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		compress := false
+		if value := r.URL.Query().Get(queryArgGzip); value != "" {
+			var err error
+			if compress, err = strconv.ParseBool(value); err != nil {
+				respond(w, "", http.StatusBadRequest, []byte(fmt.Sprintf("bad '%s' param value", queryArgGzip)))
+				return
+			}
+		}
+
+		started := false
+		err := a.storage.Backup(func(s db.Snapshot) error {
+			name := fmt.Sprintf(backupFileTemplate, time.Now().UTC().Format(backupTimeFormat))
+			contentType := "application/octet-stream"
+			if compress {
+				name += ".gz"
+				contentType = "application/gzip"
+			}
+			// the checksum is calculated while streaming, so the snapshot is read once.
+			// The trailers are sent with the chunked body only, so there is no Content-Length
+			w.Header().Set("Trailer", ChecksumHeader)
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+			w.WriteHeader(http.StatusOK)
+
+			started = true
+			h := sha256.New()
+			if err := db.WriteSnapshot(io.MultiWriter(w, h), s, compress); err != nil {
+				return err
+			}
+			w.Header().Set(ChecksumHeader, hex.EncodeToString(h.Sum(nil)))
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		if started {
+			log.WithError(err).Error("Unable to send the backup")
+			return
+		}
+		respond(w, "", http.StatusInternalServerError, errToBytes(err))
+	}
+}
@@ -0,0 +1,50 @@
+package packageapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/release"
+)
+
+type checkResponse struct {
+	*release.CheckResult
+	Error string `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *checkResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+func (a *application) checkHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &checkResponse{}
+		mode := release.CheckReport
+		if r.Method == http.MethodPost {
+			var err error
+			if mode, err = release.ParseCheckMode(r.URL.Query().Get(queryArgMode)); err != nil {
+				resp.Error = err.Error()
+				respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+				return
+			}
+		}
+
+		entry := audit.Entry{
+			Actor:  requestActor(r),
+			Action: string(mode),
+			Reason: r.URL.Query().Get(queryArgReason),
+			IP:     remoteHost(r),
+		}
+		result, err := a.releases.Check(mode, audit.Hook(entry))
+		if err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
+			return
+		}
+		resp.CheckResult = result
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
@@ -0,0 +1,64 @@
+package packageapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+type historyResponse struct {
+	Versions []release.Version `json:"versions,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *historyResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+func (a *application) historyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &historyResponse{}
+		query := r.URL.Query()
+		platform, err := gapps.PlatformString(query.Get(queryArgArch))
+		if err != nil {
+			resp.Error = fmt.Sprintf("unable to parse '%s' param: %s", queryArgArch, err)
+			respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+			return
+		}
+		date := query.Get(queryArgDate)
+		if _, err = time.Parse(models.DateOnlyFormat, date); err != nil {
+			resp.Error = fmt.Sprintf("unable to parse '%s' param: bad date format", queryArgDate)
+			respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+			return
+		}
+
+		versions, err := a.releases.History(date, platform)
+		if err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
+			return
+		}
+		resp.Versions = versions
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
+
+// parseAsOf parses the time as either the unix timestamp or RFC 3339
+func parseAsOf(value string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse '%s' param: expected unix timestamp or RFC 3339 time", queryArgAsOf)
+	}
+	return t, nil
+}
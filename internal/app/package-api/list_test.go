@@ -0,0 +1,37 @@
+package packageapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListBadValue(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	releases, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	record := &release.Record{ArchRecord: models.ArchRecord{Date: "20200101"}, Platform: gapps.PlatformArm}
+	require.NoError(t, releases.SaveRelease(record))
+	require.NoError(t, storage.Put(release.Key("20200102", gapps.PlatformArm), []byte("{")))
+
+	// the corrupt value doesn't stop serving the others
+	a := &application{storage: storage, releases: releases}
+	w := httptest.NewRecorder()
+	a.listHandler()(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp models.ListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "20200101", resp.ArchList[gapps.PlatformArm.String()].Date)
+}
@@ -1,6 +1,7 @@
 package packageapi
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -15,8 +16,15 @@ import (
 const (
 	authHeader = "Authorization"
 	authFormat = "Bearer %s"
+
+	// defaultActor is the name of the auth_key in the audit log
+	defaultActor = "default"
 )
 
+type contextKey int
+
+const actorContextKey contextKey = iota
+
 func withMiddlewares(next http.Handler) http.Handler {
 	return handlers.CORS(
 		handlers.AllowedHeaders([]string{"*"}),
@@ -34,18 +42,58 @@ func withMiddlewares(next http.Handler) http.Handler {
 	)
 }
 
-// authMiddleware checks basic authentication header
-func authMiddleware(authKey string, next http.Handler) http.Handler {
+// countingBody counts the bytes read from the request body
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// limitBody limits the request body with http.MaxBytesReader.
+// The returned function reports if the body exceeded the limit, as Go 1.18 has no typed error for it
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) func() bool {
+	body := &countingBody{ReadCloser: r.Body}
+	r.Body = http.MaxBytesReader(w, body, limit)
+	// MaxBytesReader reads one byte over the limit to detect it
+	return func() bool { return body.n > limit }
+}
+
+// authMiddleware checks basic authentication header against the keys of the actors.
+// The name of the actor is passed in the request context
+func authMiddleware(authKeys map[string]string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.EqualFold(r.Header.Get(authHeader), fmt.Sprintf(authFormat, authKey)) {
-			respond(w, "", http.StatusUnauthorized, nil)
-			return
+		header := r.Header.Get(authHeader)
+		for actor, key := range authKeys {
+			if key != "" && strings.EqualFold(header, fmt.Sprintf(authFormat, key)) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorContextKey, actor)))
+				return
+			}
 		}
 
-		next.ServeHTTP(w, r)
+		respond(w, "", http.StatusUnauthorized, nil)
 	})
 }
 
+// requestActor returns the name of the actor authenticated by authMiddleware
+func requestActor(r *http.Request) string {
+	actor, _ := r.Context().Value(actorContextKey).(string)
+	return actor
+}
+
+// remoteHost returns the host of the request client
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // logFormatter impelements handlers.LogFormatter
 func logFormatter(_ io.Writer, params handlers.LogFormatterParams) {
 	fields := log.Fields{
@@ -54,14 +102,9 @@ func logFormatter(_ io.Writer, params handlers.LogFormatterParams) {
 		"URL":    params.Request.RequestURI,
 		"Code":   params.StatusCode,
 		"Size":   params.Size,
+		"Host":   remoteHost(params.Request),
 	}
 
-	host, _, err := net.SplitHostPort(params.Request.RemoteAddr)
-	if err != nil {
-		host = params.Request.RemoteAddr
-	}
-	fields["Host"] = host
-
 	status := ""
 	if params.StatusCode != http.StatusOK {
 		status = http.StatusText(params.StatusCode)
@@ -3,6 +3,10 @@ package packageapi
 import (
 	"errors"
 
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/internal/pkg/replication"
+
 	"github.com/spf13/viper"
 )
 
@@ -20,8 +24,19 @@ func WithConfig(cfg *viper.Viper) Option {
 	}
 }
 
-// WithStorage provides Storage to the client
-func WithStorage(storage Storage) Option {
+// WithRepository provides release Repository to the client
+func WithRepository(repo *release.Repository) Option {
+	return func(c *application) error {
+		if repo == nil {
+			return errors.New("repository is nil")
+		}
+		c.releases = repo
+		return nil
+	}
+}
+
+// WithStorage provides the Storage to the client for the maintenance endpoints
+func WithStorage(storage db.Storage) Option {
 	return func(c *application) error {
 		if storage == nil {
 			return errors.New("storage is nil")
@@ -30,3 +45,33 @@ func WithStorage(storage Storage) Option {
 		return nil
 	}
 }
+
+// WithReadOnly disables the admin changes, as the data is written by the other process
+func WithReadOnly() Option {
+	return func(c *application) error {
+		c.readOnly = true
+		return nil
+	}
+}
+
+// WithFollower provides the replication Follower to the client for the status endpoint
+func WithFollower(f *replication.Follower) Option {
+	return func(c *application) error {
+		if f == nil {
+			return errors.New("follower is nil")
+		}
+		c.follower = f
+		return nil
+	}
+}
+
+// WithChecker provides the release Checker to the client for the refresh and webhook endpoints
+func WithChecker(c Checker) Option {
+	return func(a *application) error {
+		if c == nil {
+			return errors.New("checker is nil")
+		}
+		a.checker = c
+		return nil
+	}
+}
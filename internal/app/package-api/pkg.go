@@ -3,24 +3,29 @@ package packageapi
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
 	"github.com/opengapps/package-api/pkg/gapps"
 )
 
 const (
-	actionEnable    = "enable"
-	actionDisable   = "disable"
-	gappsDateFormat = "20060102"
+	actionEnable  = "enable"
+	actionDisable = "disable"
+	actionPin     = "pin"
+	actionUnpin   = "unpin"
 )
 
 type pkgRequest struct {
 	Action   string `json:"action"`
 	Platform string `json:"platform,omitempty"`
 	Date     string `json:"date"`
+	Reason   string `json:"reason,omitempty"`
 }
 
 // Validate checks if the package request fields are valid
@@ -29,7 +34,9 @@ func (r *pkgRequest) Validate() error {
 		return errors.New("request is empty")
 	}
 
-	if r.Action != actionEnable && r.Action != actionDisable {
+	switch r.Action {
+	case actionEnable, actionDisable, actionPin, actionUnpin:
+	default:
 		return errors.New("bad Action value")
 	}
 
@@ -39,16 +46,66 @@ func (r *pkgRequest) Validate() error {
 		}
 	}
 
-	if _, err := time.Parse(gappsDateFormat, r.Date); err != nil {
+	if _, err := time.Parse(models.DateOnlyFormat, r.Date); err != nil {
 		return errors.New("bad Date format")
 	}
 
 	return nil
 }
 
+// Filter returns the release filter for the package request
+func (r *pkgRequest) Filter() release.Filter {
+	filter := release.Filter{Date: r.Date, IncludeDisabled: true}
+	if platform, err := gapps.PlatformString(r.Platform); err == nil {
+		filter.Platforms = []gapps.Platform{platform}
+	}
+	return filter
+}
+
+// apply changes the record according to the action, reporting if it was changed
+func (r *pkgRequest) apply(record *release.Record) (bool, error) {
+	flag, value := &record.Disabled, r.Action == actionDisable
+	if r.Action == actionPin || r.Action == actionUnpin {
+		flag, value = &record.Pinned, r.Action == actionPin
+	}
+	if *flag == value {
+		return false, nil
+	}
+	*flag = value
+	record.Source = release.SourceAdmin
+	return true, nil
+}
+
+// pkgRecord describes the admin state of the release
+type pkgRecord struct {
+	Key      string `json:"key"`
+	Platform string `json:"platform"`
+	Date     string `json:"date"`
+	Disabled bool   `json:"disabled"`
+	Pinned   bool   `json:"pinned"`
+	Revision uint64 `json:"rev"`
+}
+
+func newPkgRecords(records []release.Record) []pkgRecord {
+	result := make([]pkgRecord, 0, len(records))
+	for i := range records {
+		result = append(result, pkgRecord{
+			Key:      records[i].Key(),
+			Platform: records[i].Platform.String(),
+			Date:     records[i].Date,
+			Disabled: records[i].Disabled,
+			Pinned:   records[i].Pinned,
+			Revision: records[i].Revision,
+		})
+	}
+	return result
+}
+
 type pkgResponse struct {
-	Status string `json:"status,omitempty"`
-	Error  string `json:"error,omitempty"`
+	Status   string      `json:"status,omitempty"`
+	Changed  []string    `json:"changed,omitempty"`
+	Releases []pkgRecord `json:"releases,omitempty"`
+	Error    string      `json:"error,omitempty"`
 }
 
 // ToJSON forms JSON body from a struct, ignoring Marshal error
@@ -57,6 +114,56 @@ func (r *pkgResponse) ToJSON() []byte {
 	return body
 }
 
+// parseIfMatch returns the list of the entity tags from the If-Match header
+func parseIfMatch(r *http.Request) []string {
+	var etags []string
+	for _, value := range r.Header.Values("If-Match") {
+		for _, etag := range strings.Split(value, ",") {
+			if etag = strings.TrimSpace(etag); etag != "" {
+				etags = append(etags, etag)
+			}
+		}
+	}
+	return etags
+}
+
+func (a *application) pkgStateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &pkgResponse{}
+		query := r.URL.Query()
+		req := &pkgRequest{Platform: query.Get(queryArgArch), Date: query.Get(queryArgDate)}
+		if req.Platform != "" {
+			if _, err := gapps.PlatformString(req.Platform); err != nil {
+				resp.Error = fmt.Sprintf("unable to parse '%s' param: %s", queryArgArch, err)
+				respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+				return
+			}
+		}
+		if _, err := time.Parse(models.DateOnlyFormat, req.Date); err != nil {
+			resp.Error = fmt.Sprintf("unable to parse '%s' param: bad date format", queryArgDate)
+			respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+			return
+		}
+
+		records, err := a.releases.ListReleases(req.Filter())
+		if err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
+			return
+		}
+		if len(records) == 0 {
+			resp.Error = "package with such date was not found"
+			respondJSON(w, http.StatusNotFound, resp.ToJSON())
+			return
+		}
+
+		w.Header().Set("ETag", release.ETag(records))
+		resp.Releases = newPkgRecords(records)
+		resp.Status = "OK"
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
+
 func (a *application) pkgHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// unmarshal and validate request
@@ -76,69 +183,37 @@ func (a *application) pkgHandler() http.HandlerFunc {
 			return
 		}
 
-		// get the release keys from the DB
-		allKeys, err := a.storage.Keys()
+		// update all of the matching releases at once if they weren't changed since they were read,
+		// together with the audit entry
+		entry := audit.Entry{
+			Actor:  requestActor(r),
+			Action: req.Action,
+			Reason: req.Reason,
+			IP:     remoteHost(r),
+		}
+		result, err := a.releases.UpdateReleasesIf(req.Filter(), parseIfMatch(r), req.apply, audit.Hook(entry))
+		var conflict *release.ConflictError
+		if errors.As(err, &conflict) {
+			w.Header().Set("ETag", release.ETag(conflict.Current))
+			resp.Releases = newPkgRecords(conflict.Current)
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusConflict, resp.ToJSON())
+			return
+		}
 		if err != nil {
 			resp.Error = err.Error()
 			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
 			return
 		}
-
-		var keys []string
-		for i := range allKeys {
-			prefix := req.Date
-			if req.Platform != "" {
-				prefix += "-" + req.Platform
-			}
-			if strings.HasPrefix(allKeys[i], prefix) {
-				keys = append(keys, allKeys[i])
-			}
-		}
-		if len(keys) == 0 {
+		if len(result.Matched) == 0 {
 			resp.Error = "package with such date was not found"
 			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
 			return
 		}
 
-		for _, key := range keys {
-			data, err := a.storage.Get(key)
-			if err != nil {
-				resp.Error = err.Error()
-				respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
-				return
-			}
-
-			var record db.Record
-			if err = json.Unmarshal(data, &record); err != nil {
-				resp.Error = err.Error()
-				respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
-				return
-			}
-
-			switch req.Action {
-			case actionEnable:
-				if record.Disabled {
-					record.Disabled = false
-				}
-			case actionDisable:
-				if !record.Disabled {
-					record.Disabled = true
-				}
-			}
-
-			if data, err = json.Marshal(record); err != nil {
-				resp.Error = err.Error()
-				respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
-				return
-			}
-
-			if err = a.storage.Put(key, data); err != nil {
-				resp.Error = err.Error()
-				respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
-				return
-			}
-		}
-
+		w.Header().Set("ETag", release.ETag(result.Records))
+		resp.Changed = result.Changed
+		resp.Releases = newPkgRecords(result.Records)
 		resp.Status = "OK"
 		respondJSON(w, http.StatusOK, resp.ToJSON())
 	}
@@ -0,0 +1,70 @@
+package packageapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/pkg/gapps"
+	"github.com/opengapps/package-api/pkg/github"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// refreshWriteMargin is left of the HTTP timeout to send the results of the check
+const refreshWriteMargin = 500 * time.Millisecond
+
+type refreshResponse struct {
+	*github.CheckSummary
+	Error string `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *refreshResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+// refreshHandler checks the platforms for the latest releases right away and returns the results.
+// The check of the platform in progress is joined instead of starting the new one
+func (a *application) refreshHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &refreshResponse{}
+		var platforms []gapps.Platform
+		if arch := r.URL.Query().Get(queryArgArch); arch != "" {
+			platform, err := gapps.PlatformString(arch)
+			if err != nil {
+				resp.Error = fmt.Sprintf("unable to parse '%s' param: %s", queryArgArch, err)
+				respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+				return
+			}
+			platforms = append(platforms, platform)
+		}
+
+		// the response stops waiting for the checks before the write timeout of the server, so the results are still sent.
+		// The retries of the check may take longer, they go on in background then
+		timeout := a.cfg.GetDuration(config.HTTPTimeoutKey)
+		if timeout > 2*refreshWriteMargin {
+			timeout -= refreshWriteMargin
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		log.WithField("actor", requestActor(r)).WithField("platforms", platforms).Info("Refreshing the latest releases")
+		resp.CheckSummary = a.checker.Check(ctx, platforms...)
+		resp.CheckSummary.Log()
+		if err := resp.CheckSummary.Err(); err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusBadGateway, resp.ToJSON())
+			return
+		}
+		if len(resp.CheckSummary.Platforms(github.StatusInProgress)) > 0 {
+			respondJSON(w, http.StatusAccepted, resp.ToJSON())
+			return
+		}
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
@@ -0,0 +1,41 @@
+package packageapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/replication"
+)
+
+type statusResponse struct {
+	Cache   *db.CacheStats   `json:"cache,omitempty"`
+	Replica *db.ReplicaStats `json:"replica,omitempty"`
+	// Follower is set if the data is pulled from the leader
+	Follower *replication.FollowerStats `json:"follower,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *statusResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+func (a *application) statusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &statusResponse{}
+		if cache, ok := a.storage.(*db.Cache); ok {
+			stats := cache.Stats()
+			resp.Cache = &stats
+		}
+		if replica, ok := a.storage.(*db.Replica); ok {
+			stats := replica.Stats()
+			resp.Replica = &stats
+		}
+		if a.follower != nil {
+			stats := a.follower.Stats()
+			resp.Follower = &stats
+		}
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
@@ -0,0 +1,79 @@
+package packageapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/release"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const actionImport = "import"
+
+type importResponse struct {
+	*release.ImportResult
+	Error string `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *importResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+func (a *application) exportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		count, err := a.releases.Export(w)
+		if err != nil {
+			// the status is already sent with the first record
+			log.WithError(err).Error("Unable to export the records")
+			return
+		}
+		log.WithField("count", count).Debug("Exported the records")
+	}
+}
+
+func (a *application) importHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &importResponse{}
+		mode := release.ImportMerge
+		if value := r.URL.Query().Get(queryArgMode); value != "" {
+			var err error
+			if mode, err = release.ParseImportMode(value); err != nil {
+				resp.Error = err.Error()
+				respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+				return
+			}
+		}
+
+		entry := audit.Entry{
+			Actor:  requestActor(r),
+			Action: actionImport,
+			Reason: r.URL.Query().Get(queryArgReason),
+			IP:     remoteHost(r),
+		}
+		// the whole import is kept in memory until it's saved in a single transaction
+		limit := a.cfg.GetInt64(config.ImportMaxSizeKey)
+		tooLarge := limitBody(w, r, limit)
+		result, err := a.releases.Import(r.Body, mode, audit.Hook(entry))
+		if err != nil && tooLarge() {
+			// the truncated body fails with the misleading parsing error
+			resp.Error = fmt.Sprintf("request body is larger than %d bytes", limit)
+			respondJSON(w, http.StatusRequestEntityTooLarge, resp.ToJSON())
+			return
+		}
+		if err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+			return
+		}
+		resp.ImportResult = result
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
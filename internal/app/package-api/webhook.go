@@ -0,0 +1,144 @@
+package packageapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/pkg/gapps"
+	"github.com/opengapps/package-api/pkg/github"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	webhookEventHeader     = "X-GitHub-Event"
+	webhookSignatureHeader = "X-Hub-Signature-256"
+	webhookSignaturePrefix = "sha256="
+
+	webhookEventPing = "ping"
+	webhookEventPush = "push"
+
+	// webhookOwner owns the repositories of the platforms, their names are the platform names
+	webhookOwner = "opengapps"
+	// webhookRef is the branch of the LATEST files
+	webhookRef = "refs/heads/master"
+	// webhookMaxBody limits the size of the delivery
+	webhookMaxBody = 1 << 20
+)
+
+// Checker checks the release sources of the platforms for the new releases, all of them if none are provided
+type Checker interface {
+	Check(ctx context.Context, platforms ...gapps.Platform) *github.CheckSummary
+	// Trigger starts the check in background, it's stopped on the shutdown
+	Trigger(platforms ...gapps.Platform)
+}
+
+type webhookResponse struct {
+	Platform string `json:"platform,omitempty"`
+	// Ignored describes why the delivery doesn't trigger the check
+	Ignored string `json:"ignored,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *webhookResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+// pushEvent holds the fields of the GitHub push event used by the webhook
+type pushEvent struct {
+	Ref        string `json:"ref"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// webhookHandler accepts the GitHub push deliveries of the platform repositories
+// and checks the pushed platform in background, as GitHub waits for the response for 10 seconds only
+func (a *application) webhookHandler() http.HandlerFunc {
+	secret := []byte(a.cfg.GetString(config.GithubWebhookSecretKey))
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &webhookResponse{}
+		tooLarge := limitBody(w, r, webhookMaxBody)
+		body, err := io.ReadAll(r.Body)
+		if err != nil && tooLarge() {
+			resp.Error = fmt.Sprintf("delivery is larger than %d bytes", webhookMaxBody)
+			respondJSON(w, http.StatusRequestEntityTooLarge, resp.ToJSON())
+			return
+		}
+		if err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+			return
+		}
+		if !validSignature(secret, body, r.Header.Get(webhookSignatureHeader)) {
+			log.WithField("ip", remoteHost(r)).Warn("Webhook delivery with the bad signature")
+			resp.Error = "bad signature"
+			respondJSON(w, http.StatusUnauthorized, resp.ToJSON())
+			return
+		}
+
+		switch event := r.Header.Get(webhookEventHeader); event {
+		case webhookEventPush:
+		case webhookEventPing:
+			respondJSON(w, http.StatusOK, resp.ToJSON())
+			return
+		default:
+			resp.Ignored = fmt.Sprintf("event '%s' is not supported", event)
+			respondJSON(w, http.StatusAccepted, resp.ToJSON())
+			return
+		}
+
+		p, err := pushedPlatform(body)
+		if err != nil {
+			resp.Ignored = err.Error()
+			respondJSON(w, http.StatusAccepted, resp.ToJSON())
+			return
+		}
+		resp.Platform = p.String()
+		log.WithField("arch", p).Info("Got the push webhook, checking for the latest release")
+		a.checker.Trigger(p)
+		respondJSON(w, http.StatusAccepted, resp.ToJSON())
+	}
+}
+
+// pushedPlatform returns the platform of the repository from the push event
+func pushedPlatform(body []byte) (gapps.Platform, error) {
+	var event pushEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return 0, errors.New("unable to decode push event")
+	}
+	if event.Ref != webhookRef {
+		return 0, fmt.Errorf("ref '%s' doesn't hold the LATEST file", event.Ref)
+	}
+	owner, name, _ := strings.Cut(event.Repository.FullName, "/")
+	p, err := gapps.PlatformString(name)
+	if owner != webhookOwner || err != nil {
+		return 0, fmt.Errorf("repository '%s' doesn't belong to the platform", event.Repository.FullName)
+	}
+	return p, nil
+}
+
+// validSignature checks the HMAC of the body in the X-Hub-Signature-256 header format
+func validSignature(secret, body []byte, signature string) bool {
+	if len(secret) == 0 || !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return false
+	}
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, webhookSignaturePrefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
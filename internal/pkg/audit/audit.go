@@ -0,0 +1,171 @@
+// Package audit keeps the append-only log of the administrative changes
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+)
+
+const (
+	// Bucket holds the audit entries, keyed by their time
+	Bucket = "audit"
+
+	// keyFormat keeps the zeros, so the keys are sorted by time
+	keyFormat = "20060102T150405.000000000Z"
+	// keyMax is bigger than any of the key characters after the date, it's used for the inclusive upper bound
+	keyMax = "~"
+)
+
+// Entry describes the single administrative action
+type Entry struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	Changes []Change  `json:"changes"`
+	Reason  string    `json:"reason,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+}
+
+// Change describes the state of the release before and after the action
+type Change struct {
+	Key            string `json:"key"`
+	Added          bool   `json:"added,omitempty"`
+	Removed        bool   `json:"removed,omitempty"`
+	DisabledBefore bool   `json:"disabled_before"`
+	DisabledAfter  bool   `json:"disabled_after"`
+	PinnedBefore   bool   `json:"pinned_before,omitempty"`
+	PinnedAfter    bool   `json:"pinned_after,omitempty"`
+}
+
+// Filter describes the subset of entries returned by List
+type Filter struct {
+	// From and To limit the result to the entries between the dates in models.DateOnlyFormat, both inclusive.
+	// The range is open from the side of the empty date
+	From, To string
+	// Action limits the result to the entries of the action if it's not empty
+	Action string
+}
+
+// Validate checks if the filter dates are valid
+func (f *Filter) Validate() error {
+	for _, date := range []string{f.From, f.To} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(models.DateOnlyFormat, date); err != nil {
+			return fmt.Errorf("bad date '%s'", date)
+		}
+	}
+	return nil
+}
+
+// Log provides the access to the audit entries in the storage.
+// The entries are never changed or removed once they are added
+type Log struct {
+	storage db.Storage
+}
+
+// New creates new instance of Log
+func New(storage db.Storage) (*Log, error) {
+	if storage == nil {
+		return nil, errors.New("storage is nil")
+	}
+	return &Log{storage: storage}, nil
+}
+
+// Hook returns the release.CommitHook which adds the entry with the saved changes
+// in the same transaction, so the changes are never saved without the entry.
+// The commit without changes adds no entry
+func Hook(e Entry) release.CommitHook {
+	return func(tx db.Tx, changes []release.Change) error {
+		if len(changes) == 0 {
+			return nil
+		}
+		entry := e
+		entry.Changes = make([]Change, 0, len(changes))
+		for _, c := range changes {
+			change := Change{Key: c.Key, Added: c.Before == nil, Removed: c.After == nil}
+			if c.Before != nil {
+				change.DisabledBefore = c.Before.Disabled
+				change.PinnedBefore = c.Before.Pinned
+			}
+			if c.After != nil {
+				change.DisabledAfter = c.After.Disabled
+				change.PinnedAfter = c.After.Pinned
+			}
+			entry.Changes = append(entry.Changes, change)
+		}
+		return Append(tx, &entry)
+	}
+}
+
+// Append adds the entry inside of the transaction, setting its ID and time
+func Append(tx db.Tx, e *Entry) error {
+	b, err := tx.Bucket(Bucket)
+	if err != nil {
+		return fmt.Errorf("unable to open audit bucket: %w", err)
+	}
+
+	e.Time = time.Now().UTC()
+	for {
+		e.ID = e.Time.Format(keyFormat)
+		_, err = b.Get(e.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to get audit entry '%s': %w", e.ID, err)
+		}
+		// the entries made at the same time are kept in order
+		e.Time = e.Time.Add(time.Nanosecond)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("unable to encode audit entry '%s': %w", e.ID, err)
+	}
+	if err = b.Put(e.ID, data); err != nil {
+		return fmt.Errorf("unable to put audit entry '%s': %w", e.ID, err)
+	}
+	return nil
+}
+
+// List returns the entries matching the filter, sorted by time
+func (l *Log) List(f Filter) ([]Entry, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	to := ""
+	if f.To != "" {
+		to = f.To + keyMax
+	}
+
+	entries := []Entry{}
+	err := l.storage.View(func(tx db.Tx) error {
+		b, err := tx.Bucket(Bucket)
+		if err != nil {
+			return err
+		}
+		return b.Scan(f.From, to, false, func(key string, value []byte) (bool, error) {
+			var e Entry
+			if err := json.Unmarshal(value, &e); err != nil {
+				return false, fmt.Errorf("unable to parse audit entry '%s': %w", key, err)
+			}
+			if f.Action == "" || e.Action == f.Action {
+				entries = append(entries, e)
+			}
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
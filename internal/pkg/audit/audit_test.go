@@ -0,0 +1,102 @@
+package audit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/audit"
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHook(t *testing.T) {
+	log.SetLevel(log.FatalLevel) // ignore DB logging for tests
+	storage := db.NewMemory()
+	defer storage.Close(true)
+	repo, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	auditLog, err := audit.New(storage)
+	require.NoError(t, err)
+
+	for _, p := range []gapps.Platform{gapps.PlatformArm, gapps.PlatformArm64} {
+		require.NoError(t, repo.SaveRelease(&release.Record{ArchRecord: models.ArchRecord{Date: "20200101"}, Platform: p}))
+	}
+	disable := func(record *release.Record) (bool, error) {
+		record.Disabled = true
+		return true, nil
+	}
+	entry := audit.Entry{Actor: "admin", Action: "disable", Reason: "broken", IP: "127.0.0.1"}
+	_, err = repo.UpdateReleases(release.Filter{Date: "20200101"}, disable, audit.Hook(entry))
+	require.NoError(t, err)
+
+	// the failed update leaves no entry
+	_, err = repo.UpdateReleases(release.Filter{IncludeDisabled: true}, func(*release.Record) (bool, error) {
+		return false, assert.AnError
+	}, audit.Hook(audit.Entry{Action: "enable"}))
+	require.ErrorIs(t, err, assert.AnError)
+
+	// the update without changes leaves no entry
+	_, err = repo.UpdateReleases(release.Filter{Date: "20200101"}, func(*release.Record) (bool, error) {
+		return false, nil
+	}, audit.Hook(audit.Entry{Action: "noop"}))
+	require.NoError(t, err)
+
+	entries, err := auditLog.List(audit.Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "admin", entries[0].Actor)
+	assert.Equal(t, "broken", entries[0].Reason)
+	assert.Equal(t, []audit.Change{
+		{Key: "arm/20200101", DisabledAfter: true},
+		{Key: "arm64/20200101", DisabledAfter: true},
+	}, entries[0].Changes)
+}
+
+func TestList(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storage := db.NewMemory()
+	defer storage.Close(true)
+	auditLog, err := audit.New(storage)
+	require.NoError(t, err)
+
+	for _, action := range []string{"disable", "enable", "disable"} {
+		require.NoError(t, storage.Update(func(tx db.Tx) error {
+			return audit.Append(tx, &audit.Entry{Action: action})
+		}))
+	}
+	today := time.Now().UTC().Format(models.DateOnlyFormat)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(models.DateOnlyFormat)
+
+	cases := []struct {
+		name   string
+		filter audit.Filter
+		want   int
+	}{
+		{"all", audit.Filter{}, 3},
+		{"action", audit.Filter{Action: "disable"}, 2},
+		{"today", audit.Filter{From: today, To: today}, 3},
+		{"until today", audit.Filter{To: today, Action: "enable"}, 1},
+		{"tomorrow", audit.Filter{From: tomorrow}, 0},
+	}
+	for _, c := range cases {
+		entries, err := auditLog.List(c.filter)
+		require.NoError(t, err, c.name)
+		assert.Len(t, entries, c.want, c.name)
+	}
+
+	// entries are sorted and unique
+	entries, err := auditLog.List(audit.Filter{})
+	require.NoError(t, err)
+	for i := 1; i < len(entries); i++ {
+		assert.Less(t, entries[i-1].ID, entries[i].ID)
+	}
+
+	_, err = auditLog.List(audit.Filter{From: "2020-01-01"})
+	assert.Error(t, err)
+}
@@ -9,20 +9,52 @@ import (
 
 // Config keys and default values
 const (
+	ModeKey                = "mode"
 	APIHostKey             = "api_host"
 	ServerHostKey          = "server_host"
 	ServerPortKey          = "server_port"
 	HTTPTimeoutKey         = "http_timeout"
 	HTTPSRedirectKey       = "https_redirect"
+	ImportMaxSizeKey       = "import_max_size"
 	AuthKey                = "auth_key"
+	AuthKeysKey            = "auth_keys"
+	DBDriverKey            = "db.driver"
 	DBPathKey              = "db.path"
 	DBTimeoutKey           = "db.timeout"
+	DBCacheKey             = "db.cache"
 	DownloadEndpointKey    = "endpoint.download"
 	ListEndpointKey        = "endpoint.list"
 	RSSEndpointKey         = "endpoint.rss"
 	PkgEndpointKey         = "endpoint.pkg"
+	BackupEndpointKey      = "endpoint.backup"
+	ExportEndpointKey      = "endpoint.export"
+	ImportEndpointKey      = "endpoint.import"
+	AuditEndpointKey       = "endpoint.audit"
+	StatusEndpointKey      = "endpoint.status"
+	HistoryEndpointKey     = "endpoint.history"
+	CheckEndpointKey       = "endpoint.check"
+	ChangesEndpointKey     = "endpoint.changes"
+	WebhookEndpointKey     = "endpoint.webhook"
+	RefreshEndpointKey     = "endpoint.refresh"
 	GithubTokenKey         = "github.token"
 	GithubWatchIntervalKey = "github.watch_interval"
+	GithubRetriesKey       = "github.retries"
+	GithubBackoffKey       = "github.backoff"
+	GithubWebhookSecretKey = "github.webhook_secret"
+	GithubRemoveMissingKey = "github.remove_missing"
+	SourceTypeKey          = "source.type"
+	SourceURLKey           = "source.url"
+	SourceDirKey           = "source.dir"
+	SourcePlatformsKey     = "source.platforms"
+	RetentionKeepLastKey   = "retention.keep_last"
+	RetentionKeepAfterKey  = "retention.keep_after"
+	RetentionIntervalKey   = "retention.interval"
+	ReplicaEnabledKey      = "replica.enabled"
+	ReplicaSnapshotKey     = "replica.snapshot"
+	ReplicaIntervalKey     = "replica.interval"
+	ReplicationLeaderKey   = "replication.leader"
+	ReplicationKeyKey      = "replication.key"
+	ReplicationIntervalKey = "replication.interval"
 
 	RSSNameKey          = "rss.name"
 	RSSDescriptionKey   = "rss.description"
@@ -34,23 +66,44 @@ const (
 	RSSContentKey       = "rss.content"
 	RSSHistoryLengthKey = "rss.history_length"
 
+	DefaultMode                = "all"
 	DefaultServerHost          = "127.0.0.1"
 	DefaultServerPort          = "8080"
 	DefaultHTTPTimeout         = "3s"
 	DefaultHTTPSRedirect       = false
+	DefaultImportMaxSize       = 256 << 20
+	DefaultDBDriver            = "bolt"
 	DefaultDBPath              = "bolt.db"
 	DefaultDBTimeout           = "1s"
+	DefaultDBCache             = false
 	DefaultDLEndpointPath      = "/download"
 	DefaultListEndpointPath    = "/list"
 	DefaultRSSEndpointPath     = "/rss/{arch}"
 	DefaultPkgEndpointPath     = "/pkg"
+	DefaultBackupEndpointPath  = "/backup"
+	DefaultExportEndpointPath  = "/export"
+	DefaultImportEndpointPath  = "/import"
+	DefaultAuditEndpointPath   = "/audit"
+	DefaultStatusEndpointPath  = "/status"
+	DefaultHistoryEndpointPath = "/history"
+	DefaultCheckEndpointPath   = "/check"
+	DefaultChangesEndpointPath = "/changes"
+	DefaultWebhookEndpointPath = "/webhook"
+	DefaultRefreshEndpointPath = "/refresh"
 	DefaultGithubWatchInterval = "1m"
+	DefaultGithubRetries       = 3
+	DefaultGithubBackoff       = "1s"
+	DefaultGithubRemoveMissing = false
+	DefaultSourceType          = "github"
+	DefaultRetentionInterval   = "24h"
+	DefaultReplicaEnabled      = false
+	DefaultReplicaInterval     = "30s"
+	DefaultReplicationInterval = "10s"
 	DefaultRSSHistoryLength    = 3
 )
 
 var mandatoryKeys = []string{
 	AuthKey,
-	GithubTokenKey,
 	RSSNameKey,
 	RSSDescriptionKey,
 	RSSAuthorKey,
@@ -87,18 +140,40 @@ func New(name, prefix string) (*viper.Viper, error) {
 	}
 
 	// set defaults
+	cfg.SetDefault(ModeKey, DefaultMode)
 	cfg.SetDefault(APIHostKey, DefaultServerHost)
 	cfg.SetDefault(ServerHostKey, DefaultServerHost)
 	cfg.SetDefault(ServerPortKey, DefaultServerPort)
 	cfg.SetDefault(HTTPTimeoutKey, DefaultHTTPTimeout)
 	cfg.SetDefault(HTTPSRedirectKey, DefaultHTTPSRedirect)
+	cfg.SetDefault(ImportMaxSizeKey, DefaultImportMaxSize)
+	cfg.SetDefault(DBDriverKey, DefaultDBDriver)
 	cfg.SetDefault(DBPathKey, DefaultDBPath)
 	cfg.SetDefault(DBTimeoutKey, DefaultDBTimeout)
+	cfg.SetDefault(DBCacheKey, DefaultDBCache)
 	cfg.SetDefault(DownloadEndpointKey, DefaultDLEndpointPath)
 	cfg.SetDefault(ListEndpointKey, DefaultListEndpointPath)
 	cfg.SetDefault(RSSEndpointKey, DefaultRSSEndpointPath)
 	cfg.SetDefault(PkgEndpointKey, DefaultPkgEndpointPath)
+	cfg.SetDefault(BackupEndpointKey, DefaultBackupEndpointPath)
+	cfg.SetDefault(ExportEndpointKey, DefaultExportEndpointPath)
+	cfg.SetDefault(ImportEndpointKey, DefaultImportEndpointPath)
+	cfg.SetDefault(AuditEndpointKey, DefaultAuditEndpointPath)
+	cfg.SetDefault(StatusEndpointKey, DefaultStatusEndpointPath)
+	cfg.SetDefault(HistoryEndpointKey, DefaultHistoryEndpointPath)
+	cfg.SetDefault(CheckEndpointKey, DefaultCheckEndpointPath)
+	cfg.SetDefault(ChangesEndpointKey, DefaultChangesEndpointPath)
+	cfg.SetDefault(WebhookEndpointKey, DefaultWebhookEndpointPath)
+	cfg.SetDefault(RefreshEndpointKey, DefaultRefreshEndpointPath)
 	cfg.SetDefault(GithubWatchIntervalKey, DefaultGithubWatchInterval)
+	cfg.SetDefault(GithubRetriesKey, DefaultGithubRetries)
+	cfg.SetDefault(GithubBackoffKey, DefaultGithubBackoff)
+	cfg.SetDefault(GithubRemoveMissingKey, DefaultGithubRemoveMissing)
+	cfg.SetDefault(SourceTypeKey, DefaultSourceType)
+	cfg.SetDefault(RetentionIntervalKey, DefaultRetentionInterval)
+	cfg.SetDefault(ReplicaEnabledKey, DefaultReplicaEnabled)
+	cfg.SetDefault(ReplicaIntervalKey, DefaultReplicaInterval)
+	cfg.SetDefault(ReplicationIntervalKey, DefaultReplicationInterval)
 	cfg.SetDefault(RSSHistoryLengthKey, DefaultRSSHistoryLength)
 
 	// print contents in debug mode
@@ -20,21 +20,42 @@ const (
 )
 
 var testConfigKeys = map[string]interface{}{
+	config.ModeKey:                config.DefaultMode,
 	config.APIHostKey:             config.DefaultServerHost,
 	config.ServerHostKey:          config.DefaultServerHost,
 	config.ServerPortKey:          config.DefaultServerPort,
+	config.ImportMaxSizeKey:       config.DefaultImportMaxSize,
+	config.DBDriverKey:            config.DefaultDBDriver,
 	config.DBPathKey:              config.DefaultDBPath,
 	config.DBTimeoutKey:           config.DefaultDBTimeout,
+	config.DBCacheKey:             config.DefaultDBCache,
 	config.DownloadEndpointKey:    config.DefaultDLEndpointPath,
 	config.ListEndpointKey:        config.DefaultListEndpointPath,
 	config.RSSEndpointKey:         config.DefaultRSSEndpointPath,
+	config.BackupEndpointKey:      config.DefaultBackupEndpointPath,
+	config.ExportEndpointKey:      config.DefaultExportEndpointPath,
+	config.ImportEndpointKey:      config.DefaultImportEndpointPath,
+	config.AuditEndpointKey:       config.DefaultAuditEndpointPath,
+	config.StatusEndpointKey:      config.DefaultStatusEndpointPath,
+	config.HistoryEndpointKey:     config.DefaultHistoryEndpointPath,
+	config.CheckEndpointKey:       config.DefaultCheckEndpointPath,
+	config.ChangesEndpointKey:     config.DefaultChangesEndpointPath,
+	config.WebhookEndpointKey:     config.DefaultWebhookEndpointPath,
+	config.RefreshEndpointKey:     config.DefaultRefreshEndpointPath,
 	config.GithubWatchIntervalKey: config.DefaultGithubWatchInterval,
+	config.GithubRetriesKey:       config.DefaultGithubRetries,
+	config.GithubBackoffKey:       config.DefaultGithubBackoff,
+	config.GithubRemoveMissingKey: config.DefaultGithubRemoveMissing,
+	config.SourceTypeKey:          config.DefaultSourceType,
+	config.RetentionIntervalKey:   config.DefaultRetentionInterval,
+	config.ReplicaEnabledKey:      config.DefaultReplicaEnabled,
+	config.ReplicaIntervalKey:     config.DefaultReplicaInterval,
+	config.ReplicationIntervalKey: config.DefaultReplicationInterval,
 	config.RSSHistoryLengthKey:    config.DefaultRSSHistoryLength,
 }
 
 var testConfigEnvs = map[string]string{
 	config.AuthKey:           testValueString,
-	config.GithubTokenKey:    testValueString,
 	config.RSSNameKey:        testValueString,
 	config.RSSDescriptionKey: testValueString,
 	config.RSSAuthorKey:      testValueString,
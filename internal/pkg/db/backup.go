@@ -0,0 +1,128 @@
+package db
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+const snapshotFileName = "snapshot.db"
+
+// Snapshot describes the consistent copy of the storage file.
+// It can be written out several times, the contents stay the same
+type Snapshot interface {
+	Size() int64
+	WriteTo(w io.Writer) (int64, error)
+}
+
+// WriteSnapshot writes the snapshot, gzipped if compress is set
+func WriteSnapshot(w io.Writer, s Snapshot, compress bool) error {
+	if !compress {
+		if _, err := s.WriteTo(w); err != nil {
+			return fmt.Errorf("unable to write snapshot: %w", err)
+		}
+		return nil
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := s.WriteTo(zw); err != nil {
+		return fmt.Errorf("unable to write snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("unable to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// Backup runs the function with the snapshot of the DB file inside of the read-only transaction
+func (db *DB) Backup(fn func(s Snapshot) error) error {
+	log.Debug("Creating DB snapshot")
+	return db.b.View(func(tx *bbolt.Tx) error {
+		return fn(tx)
+	})
+}
+
+// Backup runs the function with the snapshot of the in-memory data in the BoltDB format,
+// so it can be restored with the bolt driver
+func (m *Memory) Backup(fn func(s Snapshot) error) error {
+	log.Debug("Creating in-memory DB snapshot")
+	return withTempSnapshot(func(path string) error {
+		b, err := bbolt.Open(path, openMode, nil)
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+
+		m.mtx.RLock()
+		defer m.mtx.RUnlock()
+		if err = m.check(); err != nil {
+			return err
+		}
+		return b.Update(func(tx *bbolt.Tx) error {
+			for name, bucket := range m.buckets {
+				dest, err := tx.CreateBucketIfNotExists([]byte(name))
+				if err != nil {
+					return err
+				}
+				for _, key := range bucket.keys {
+					if err = dest.Put([]byte(key), bucket.values[key]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	}, fn)
+}
+
+// Backup runs the function with the snapshot of the SQLite DB made by VACUUM INTO
+func (s *SQLite) Backup(fn func(s Snapshot) error) error {
+	log.Debug("Creating SQLite DB snapshot")
+	return withTempSnapshot(func(path string) error {
+		_, err := s.db.Exec(sqliteVacuumInto, path)
+		return err
+	}, fn)
+}
+
+// withTempSnapshot creates the snapshot file in the temporary dir and removes it after the function is done
+func withTempSnapshot(create func(path string) error, fn func(s Snapshot) error) error {
+	dir, err := os.MkdirTemp("", "package-api-backup")
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, snapshotFileName)
+	if err = create(path); err != nil {
+		return fmt.Errorf("unable to create snapshot: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot: %w", err)
+	}
+	return fn(fileSnapshot{path: path, size: info.Size()})
+}
+
+// fileSnapshot implements Snapshot for the file which is not changed anymore
+type fileSnapshot struct {
+	path string
+	size int64
+}
+
+func (s fileSnapshot) Size() int64 {
+	return s.size
+}
+
+func (s fileSnapshot) WriteTo(w io.Writer) (int64, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
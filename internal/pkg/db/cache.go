@@ -0,0 +1,253 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CacheStats holds the counters of the Cache
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+}
+
+// Cache is the read-through Storage decorator, which keeps the copy of the global bucket in memory.
+// The copy is loaded on the first read and dropped on every write to the global bucket,
+// so all of the writes must go through the Cache.
+// The named buckets are always read from the storage
+type Cache struct {
+	storage Storage
+
+	mtx    sync.RWMutex
+	global *memoryBucket // never changed once loaded, nil until the first read
+
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+}
+
+// NewCache creates new instance of Cache on top of the storage
+func NewCache(storage Storage) *Cache {
+	log.Debug("Enabling DB cache")
+	return &Cache{storage: storage}
+}
+
+// Stats returns the current values of the counters
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Invalidations: atomic.LoadUint64(&c.invalidations),
+	}
+}
+
+// Close drops the cached data and closes the storage
+func (c *Cache) Close(delete bool) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.global = nil
+	return c.storage.Close(delete)
+}
+
+// Keys returns a list of available keys, sorted alphabetically
+func (c *Cache) Keys() ([]string, error) {
+	b, err := c.load()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the list of keys from DB: %w", err)
+	}
+	return b.Keys()
+}
+
+// Get acquires value by provided key
+func (c *Cache) Get(key string) ([]byte, error) {
+	b, err := c.load()
+	if err == nil {
+		var value []byte
+		if value, err = b.Get(key); err == nil {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+}
+
+// GetMultipleBySuffix returns keys and values, for which the key contains the suffix, sorted by key
+// It returns all keys and values if the suffix is empty
+func (c *Cache) GetMultipleBySuffix(suffix string) ([]string, [][]byte, error) {
+	b, err := c.load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get values for suffix '%s' from DB: %w", suffix, err)
+	}
+
+	var (
+		keys   []string
+		values [][]byte
+	)
+	for _, k := range b.keys {
+		if strings.HasSuffix(k, suffix) {
+			value, _ := b.Get(k)
+			keys = append(keys, k)
+			values = append(values, value)
+		}
+	}
+	return keys, values, nil
+}
+
+// Scan calls the function for the keys in the [from, to) range of the cached global bucket
+func (c *Cache) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	b, err := c.load()
+	if err == nil {
+		err = b.Scan(from, to, reverse, fn)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to scan keys from '%s' to '%s' in DB: %w", from, to, err)
+	}
+	return nil
+}
+
+// View runs the function inside of the read-only transaction of the storage,
+// the global bucket is read from the cache.
+// The cache stays locked until the transaction is done, so the cached global bucket
+// and the named buckets of the storage are never read from the different writes.
+// The function must not call the Cache itself
+func (c *Cache) View(fn func(tx Tx) error) error {
+	for {
+		if _, err := c.load(); err != nil {
+			return err
+		}
+		c.mtx.RLock()
+		// the cache could be dropped by the write after the load, it's loaded again then
+		if b := c.global; b != nil {
+			defer c.mtx.RUnlock()
+			return c.storage.View(func(tx Tx) error {
+				return fn(cacheTx{readOnlyBucket: readOnlyBucket{Bucket: b}, tx: tx})
+			})
+		}
+		c.mtx.RUnlock()
+	}
+}
+
+// Put sets/updates the value by provided key
+func (c *Cache) Put(key string, val []byte) error {
+	return c.write(func() error { return c.storage.Put(key, val) })
+}
+
+// Delete removes the value by provided key
+func (c *Cache) Delete(key string) error {
+	return c.write(func() error { return c.storage.Delete(key) })
+}
+
+// Purge removes all of the values from the global bucket
+func (c *Cache) Purge() error {
+	return c.write(c.storage.Purge)
+}
+
+// Migrate applies the pending migrations of the storage
+func (c *Cache) Migrate(dryRun bool) ([]MigrationReport, error) {
+	var reports []MigrationReport
+	err := c.write(func() error {
+		var err error
+		reports, err = c.storage.Migrate(dryRun)
+		return err
+	})
+	return reports, err
+}
+
+// Update runs the function inside of the read-write transaction of the storage.
+// The cached data is dropped only if the transaction writes to the global bucket
+func (c *Cache) Update(fn func(tx Tx) error) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	changed := false
+	defer func() {
+		// the rolled back writes drop the cache too, it's reloaded on the next read
+		if changed && c.global != nil {
+			c.global = nil
+			atomic.AddUint64(&c.invalidations, 1)
+		}
+	}()
+	return c.storage.Update(func(tx Tx) error {
+		return fn(writeTx{Tx: tx, changed: &changed})
+	})
+}
+
+// Backup runs the function with the consistent snapshot of the storage
+func (c *Cache) Backup(fn func(s Snapshot) error) error {
+	return c.storage.Backup(fn)
+}
+
+// Compact reclaims the space left by the removed values in the storage
+func (c *Cache) Compact() error {
+	return c.write(c.storage.Compact)
+}
+
+// load returns the cached global bucket, loading it from the storage if necessary
+func (c *Cache) load() (*memoryBucket, error) {
+	c.mtx.RLock()
+	b := c.global
+	c.mtx.RUnlock()
+	if b != nil {
+		atomic.AddUint64(&c.hits, 1)
+		return b, nil
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.global != nil {
+		atomic.AddUint64(&c.hits, 1)
+		return c.global, nil
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+	b = newMemoryBucket()
+	err := c.storage.Scan("", "", false, func(key string, value []byte) (bool, error) {
+		return true, b.Put(key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.global = b
+	return b, nil
+}
+
+// write runs the write operation of the storage and drops the cached data.
+// The cache is locked, so it's not loaded until the write is done
+func (c *Cache) write(fn func() error) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.global != nil {
+		c.global = nil
+		atomic.AddUint64(&c.invalidations, 1)
+	}
+	return fn()
+}
+
+// cacheTx implements the read-only Tx with the cached global bucket
+type cacheTx struct {
+	readOnlyBucket
+	tx Tx
+}
+
+func (t cacheTx) Bucket(name string) (Bucket, error) {
+	return t.tx.Bucket(name)
+}
+
+// writeTx marks the global bucket as changed on its writes
+type writeTx struct {
+	Tx
+	changed *bool
+}
+
+func (t writeTx) Put(key string, val []byte) error {
+	*t.changed = true
+	return t.Tx.Put(key, val)
+}
+
+func (t writeTx) Delete(key string) error {
+	*t.changed = true
+	return t.Tx.Delete(key)
+}
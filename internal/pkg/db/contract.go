@@ -0,0 +1,60 @@
+package db
+
+// Storage describes the key-value storage for the encoded records.
+// Its behaviour is defined by the conformance suite in internal/pkg/db/storagetest
+type Storage interface {
+	Close(delete bool) error
+	Keys() ([]string, error)
+	Get(key string) ([]byte, error)
+	GetMultipleBySuffix(suffix string) ([]string, [][]byte, error)
+	Put(key string, val []byte) error
+	Delete(key string) error
+	Purge() error
+	Migrate(dryRun bool) ([]MigrationReport, error)
+	// Update runs the function inside of the read-write transaction.
+	// All of the changes are rolled back if the function returns an error, which is returned as is
+	Update(fn func(tx Tx) error) error
+	// View runs the function inside of the read-only transaction
+	View(fn func(tx Tx) error) error
+	// Scan runs Bucket.Scan for the global bucket inside of the read-only transaction.
+	// The function must not call the storage
+	Scan(from, to string, reverse bool, fn ScanFunc) error
+	// Backup runs the function with the consistent snapshot of the storage in the format of its driver.
+	// The snapshot is valid only until the function returns
+	Backup(fn func(s Snapshot) error) error
+	// Compact reclaims the space left by the removed values.
+	// The storage must not be used until it returns
+	Compact() error
+}
+
+// ScanFunc is called for every key-value pair by Scan, the scan stops if it returns false or an error
+type ScanFunc func(key string, value []byte) (bool, error)
+
+// Bucket describes the named set of key-value pairs inside of a transaction
+type Bucket interface {
+	Keys() ([]string, error)
+	Get(key string) ([]byte, error)
+	Put(key string, val []byte) error
+	Delete(key string) error
+	// Scan seeks to the start of the [from, to) range and calls the function for the keys inside of it
+	// in the ascending order, or in the descending one if reverse is set. The empty bound is open
+	Scan(from, to string, reverse bool, fn ScanFunc) error
+}
+
+// Tx describes the storage transaction.
+// The embedded Bucket methods operate on the global bucket
+type Tx interface {
+	Bucket
+	// Bucket returns the named bucket, it is created on the first write.
+	// The missing bucket is empty in the read-only transaction
+	Bucket(name string) (Bucket, error)
+}
+
+// compile-time checks of the implementations
+var (
+	_ Storage = (*DB)(nil)
+	_ Storage = (*Memory)(nil)
+	_ Storage = (*SQLite)(nil)
+	_ Storage = (*Cache)(nil)
+	_ Storage = (*Replica)(nil)
+)
@@ -8,16 +8,22 @@ import (
 	"sort"
 	"time"
 
-	"github.com/opengapps/package-api/internal/pkg/models"
 	log "github.com/sirupsen/logrus"
 	"go.etcd.io/bbolt"
 )
 
 const (
-	// KeyTemplate describes the format of the keys inside of a bucket
-	KeyTemplate = "%s-%s"
+	// DriverBolt selects the BoltDB storage
+	DriverBolt = "bolt"
+	// DriverSQLite selects the SQLite storage
+	DriverSQLite = "sqlite"
 
 	openMode = 0755
+
+	// compactSuffix is added to the DB path for the compacted copy
+	compactSuffix = ".compact"
+	// compactTxSize limits the size of the transaction copying the data
+	compactTxSize = 1 << 20
 )
 
 // Package vars
@@ -28,32 +34,37 @@ var (
 	bucketName = []byte("global")
 )
 
-// Record is used to store models.ArchRecord in DB
-type Record struct {
-	models.ArchRecord
-
-	Disabled  bool  `json:"disabled,omitempty"`
-	Timestamp int64 `json:"ts"`
-}
-
 // DB describes local BoltDB database
 type DB struct {
 	b       *bbolt.DB
 	timeout time.Duration
 }
 
-// New creates new instance of DB
-func New(path string, timeout time.Duration) (*DB, error) {
-	// open connection to the DB
-	log.WithField("path", path).WithField("timeout", timeout).Debug("Creating DB connection")
-	opts := bbolt.DefaultOptions
-	if timeout > 0 {
-		opts.Timeout = timeout
+// New creates new instance of DB and applies the pending migrations
+func New(path string, timeout time.Duration, opts ...Option) (*DB, error) {
+	settings, err := newSettings(opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply DB options: %w", err)
 	}
-	b, err := bbolt.Open(path, openMode, opts)
+
+	// open connection to the DB
+	log.WithField("path", path).WithField("timeout", timeout).WithField("read_only", settings.readOnly).
+		Debug("Creating DB connection")
+	boltOpts := boltOptions(timeout)
+	boltOpts.ReadOnly = settings.readOnly
+	b, err := bbolt.Open(path, openMode, boltOpts)
 	if err != nil {
 		return nil, fmt.Errorf("unable to open DB: %w", err)
 	}
+	if settings.readOnly {
+		db := &DB{b: b, timeout: timeout}
+		if err = checkSchemaVersion(db.View); err != nil {
+			b.Close()
+			return nil, err
+		}
+		log.Debug("Read-only DB initiated")
+		return db, nil
+	}
 
 	// create global bucket if it doesn't exist yet
 	log.WithField("bucket", string(bucketName)).Debug("Setting the default bucket")
@@ -62,15 +73,74 @@ func New(path string, timeout time.Duration) (*DB, error) {
 		return bErr
 	})
 	if err != nil {
+		b.Close()
 		return nil, fmt.Errorf("unable to create global bucket: %w", err)
 	}
 
-	// return the DB
+	// upgrade the stored data
 	db := &DB{b: b, timeout: timeout}
+	if !settings.skipMigrations {
+		if _, err = db.Migrate(false); err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
+
+	// return the DB
 	log.Debug("DB initiated")
 	return db, nil
 }
 
+// boltOptions returns the copy of the default BoltDB options with the timeout
+func boltOptions(timeout time.Duration) *bbolt.Options {
+	opts := *bbolt.DefaultOptions
+	if timeout > 0 {
+		opts.Timeout = timeout
+	}
+	return &opts
+}
+
+// Compact rewrites the DB file to reclaim the space left by the removed values.
+// The DB must not be used until it returns
+func (db *DB) Compact() error {
+	path := db.b.Path()
+	tmpPath := path + compactSuffix
+	log.WithField("path", path).Debug("Compacting the DB")
+
+	dst, err := bbolt.Open(tmpPath, openMode, boltOptions(db.timeout))
+	if err != nil {
+		return fmt.Errorf("unable to compact DB: %w", err)
+	}
+	if err = bbolt.Compact(dst, db.b, compactTxSize); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("unable to compact DB: %w", err)
+	}
+	if err = dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("unable to compact DB: %w", err)
+	}
+
+	// the compacted copy replaces the DB file
+	if err = db.b.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("unable to compact DB: %w", err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+	}
+	b, openErr := bbolt.Open(path, openMode, boltOptions(db.timeout))
+	if openErr != nil {
+		return fmt.Errorf("unable to reopen DB after compaction: %w", openErr)
+	}
+	db.b = b
+	if err != nil {
+		return fmt.Errorf("unable to compact DB: %w", err)
+	}
+	log.Debug("DB compacted")
+	return nil
+}
+
 // Close closes the DB
 func (db *DB) Close(delete bool) error {
 	log.Debug("Closing the DB")
@@ -209,14 +279,29 @@ func (db *DB) Delete(key string) error {
 	return nil
 }
 
-// Purge removes the bucket from DB
+// Purge removes all of the values from DB by recreating the global bucket
 func (db *DB) Purge() error {
 	log.Debug("Purging the DB")
 	err := db.b.Update(func(tx *bbolt.Tx) error {
-		return tx.DeleteBucket(bucketName)
+		if err := tx.DeleteBucket(bucketName); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket(bucketName)
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("unable to purge global bucket from DB: %w", err)
 	}
 	return nil
 }
+
+// Migrate applies the pending migrations inside of a single transaction.
+// In the dry-run mode the changes are rolled back and only reported
+func (db *DB) Migrate(dryRun bool) ([]MigrationReport, error) {
+	return migrate(db.Update, dryRun)
+}
+
+// Scan calls the function for the keys in the [from, to) range of the global bucket
+func (db *DB) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	return scanStorage(db.View, from, to, reverse, fn)
+}
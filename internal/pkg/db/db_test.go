@@ -0,0 +1,171 @@
+package db_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/db/storagetest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = time.Second
+
+func TestDB(t *testing.T) {
+	log.SetLevel(log.FatalLevel) // ignore DB logging for tests
+	storagetest.Run(t, func(t *testing.T) db.Storage {
+		s, err := db.New(filepath.Join(t.TempDir(), "bolt.db"), testTimeout)
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close(true) })
+		return s
+	})
+}
+
+func TestMemory(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storagetest.Run(t, func(t *testing.T) db.Storage {
+		s := db.NewMemory()
+		t.Cleanup(func() { s.Close(true) })
+		return s
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storagetest.Run(t, func(t *testing.T) db.Storage {
+		s, err := db.NewSQLite(filepath.Join(t.TempDir(), "sqlite.db"), testTimeout)
+		require.NoError(t, err)
+		t.Cleanup(func() { s.Close(true) })
+		return s
+	})
+}
+
+func TestCache(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storagetest.Run(t, func(t *testing.T) db.Storage {
+		s, err := db.New(filepath.Join(t.TempDir(), "bolt.db"), testTimeout)
+		require.NoError(t, err)
+		c := db.NewCache(s)
+		t.Cleanup(func() { c.Close(true) })
+		return c
+	})
+}
+
+func TestCacheStats(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	c := db.NewCache(db.NewMemory())
+	defer c.Close(true)
+
+	require.NoError(t, c.Put("key", []byte("value")))
+	for i := 0; i < 3; i++ {
+		value, err := c.Get("key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", string(value))
+	}
+	assert.Equal(t, db.CacheStats{Hits: 2, Misses: 1}, c.Stats())
+
+	// the writes made inside of the transactions are seen too
+	require.NoError(t, c.Update(func(tx db.Tx) error {
+		return tx.Put("key", []byte("updated"))
+	}))
+	require.NoError(t, c.View(func(tx db.Tx) error {
+		value, err := tx.Get("key")
+		require.NoError(t, err)
+		assert.Equal(t, "updated", string(value))
+		return nil
+	}))
+	assert.Equal(t, db.CacheStats{Hits: 2, Misses: 2, Invalidations: 1}, c.Stats())
+
+	// the writes to the named buckets keep the cached data
+	require.NoError(t, c.Update(func(tx db.Tx) error {
+		b, err := tx.Bucket("named")
+		if err != nil {
+			return err
+		}
+		return b.Put("key", []byte("value"))
+	}))
+	_, err := c.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, db.CacheStats{Hits: 3, Misses: 2, Invalidations: 1}, c.Stats())
+}
+
+func TestBackupRestore(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	dir := t.TempDir()
+	openBolt := func(path string) (db.Storage, error) { return db.New(path, testTimeout) }
+	openSQLite := func(path string) (db.Storage, error) { return db.NewSQLite(path, testTimeout) }
+
+	cases := []struct {
+		name    string
+		storage func() (db.Storage, error)
+		restore func(path string) (db.Storage, error)
+	}{
+		{"bolt", func() (db.Storage, error) { return openBolt(filepath.Join(dir, "bolt.db")) }, openBolt},
+		{"memory", func() (db.Storage, error) { return db.NewMemory(), nil }, openBolt},
+		{"sqlite", func() (db.Storage, error) { return openSQLite(filepath.Join(dir, "sqlite.db")) }, openSQLite},
+	}
+	for _, c := range cases {
+		s, err := c.storage()
+		require.NoError(t, err, c.name)
+		require.NoError(t, s.Put("key", []byte("value")), c.name)
+
+		path := filepath.Join(dir, c.name+"-backup.db")
+		f, err := os.Create(path)
+		require.NoError(t, err, c.name)
+		require.NoError(t, s.Backup(func(snapshot db.Snapshot) error {
+			return db.WriteSnapshot(f, snapshot, false)
+		}), c.name)
+		require.NoError(t, f.Close(), c.name)
+		require.NoError(t, s.Close(true), c.name)
+
+		restored, err := c.restore(path)
+		require.NoError(t, err, c.name)
+		value, err := restored.Get("key")
+		require.NoError(t, err, c.name)
+		require.Equal(t, "value", string(value), c.name)
+		require.NoError(t, restored.Close(true), c.name)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	dir := t.TempDir()
+	writer, err := db.New(filepath.Join(dir, "bolt.db"), testTimeout)
+	require.NoError(t, err)
+	defer writer.Close(true)
+
+	path := filepath.Join(dir, "snapshot.db")
+	_, err = db.NewReplica(path, testTimeout)
+	assert.Error(t, err, "snapshot must exist")
+
+	require.NoError(t, writer.Put("first", []byte("value")))
+	require.NoError(t, db.PublishSnapshot(writer, path))
+	replica, err := db.NewReplica(path, testTimeout)
+	require.NoError(t, err)
+	defer replica.Close(false)
+
+	value, err := replica.Get("first")
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+	assert.ErrorIs(t, replica.Put("second", []byte("value")), db.ErrReadOnly)
+	assert.ErrorIs(t, replica.Update(func(tx db.Tx) error { return nil }), db.ErrReadOnly)
+
+	reloaded, err := replica.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "snapshot was not changed")
+
+	require.NoError(t, writer.Put("second", []byte("value")))
+	require.NoError(t, db.PublishSnapshot(writer, path))
+	reloaded, err = replica.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	keys, err := replica.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, keys)
+	assert.Equal(t, uint64(2), replica.Stats().Reloads)
+}
@@ -0,0 +1,334 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+)
+
+// MemoryPath is the special DB path which selects the in-memory storage
+const MemoryPath = ":memory:"
+
+// Memory describes thread-safe in-memory storage with the same semantics as DB.
+// It is intended for tests and ephemeral deployments, the data is lost on Close
+type Memory struct {
+	mtx     sync.RWMutex
+	buckets map[string]*memoryBucket
+	closed  bool
+}
+
+// NewMemory creates new instance of Memory.
+// There is nothing to migrate, so it starts with the latest schema version
+func NewMemory() *Memory {
+	log.Debug("Creating in-memory DB")
+	return &Memory{
+		buckets: map[string]*memoryBucket{
+			string(bucketName): newMemoryBucket(),
+			MetaBucket:         newMemoryBucket(schemaVersionKey, strconv.Itoa(SchemaVersion())),
+		},
+	}
+}
+
+// Close closes the Memory, all of the data is dropped if delete is set
+func (m *Memory) Close(delete bool) error {
+	log.Debug("Closing the in-memory DB")
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.closed = true
+	if delete {
+		m.buckets = nil
+	}
+	return nil
+}
+
+// Keys returns a list of available keys, sorted alphabetically
+func (m *Memory) Keys() ([]string, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("unable to get the list of keys from DB: %w", err)
+	}
+	return m.global().Keys()
+}
+
+// Get acquires value by provided key
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	err := m.check()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+	}
+
+	value, err := m.global().Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+	}
+	return value, nil
+}
+
+// GetMultipleBySuffix returns keys and values, for which the key contains the suffix, sorted by key
+// It returns all keys and values if the suffix is empty
+func (m *Memory) GetMultipleBySuffix(suffix string) ([]string, [][]byte, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	if err := m.check(); err != nil {
+		return nil, nil, fmt.Errorf("unable to get values for suffix '%s' from DB: %w", suffix, err)
+	}
+
+	global := m.global()
+	var (
+		keys   []string
+		values [][]byte
+	)
+	for _, k := range global.keys {
+		if strings.HasSuffix(k, suffix) {
+			value, _ := global.Get(k)
+			keys = append(keys, k)
+			values = append(values, value)
+		}
+	}
+	return keys, values, nil
+}
+
+// Put sets/updates the value by provided key
+func (m *Memory) Put(key string, val []byte) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	err := m.check()
+	if err == nil {
+		err = m.global().Put(key, val)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the value by provided key
+func (m *Memory) Delete(key string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err := m.check(); err != nil {
+		return fmt.Errorf("unable to delete value for key '%s' from DB: %w", key, err)
+	}
+	return m.global().Delete(key)
+}
+
+// Purge removes all of the values from the global bucket
+func (m *Memory) Purge() error {
+	log.Debug("Purging the in-memory DB")
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err := m.check(); err != nil {
+		return fmt.Errorf("unable to purge global bucket from DB: %w", err)
+	}
+	m.buckets[string(bucketName)] = newMemoryBucket()
+	return nil
+}
+
+// Migrate applies the pending migrations inside of a single transaction.
+// In the dry-run mode the changes are rolled back and only reported
+func (m *Memory) Migrate(dryRun bool) ([]MigrationReport, error) {
+	return migrate(m.Update, dryRun)
+}
+
+// Compact does nothing, as the removed values don't take any space
+func (m *Memory) Compact() error {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.check()
+}
+
+// Update runs the function inside of the read-write transaction.
+// The changed buckets are replaced only if the function succeeds
+func (m *Memory) Update(fn func(tx Tx) error) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+
+	tx := &memoryTx{source: m.buckets, buckets: make(map[string]*memoryBucket), writable: true}
+	tx.memoryBucket = tx.bucket(string(bucketName))
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for name, b := range tx.buckets {
+		m.buckets[name] = b
+	}
+	return nil
+}
+
+// View runs the function inside of the read-only transaction
+func (m *Memory) View(fn func(tx Tx) error) error {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	if err := m.check(); err != nil {
+		return err
+	}
+	return fn(&memoryTx{memoryBucket: m.global(), source: m.buckets})
+}
+
+// Scan calls the function for the keys in the [from, to) range of the global bucket
+func (m *Memory) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	return scanStorage(m.View, from, to, reverse, fn)
+}
+
+func (m *Memory) global() *memoryBucket {
+	return m.buckets[string(bucketName)]
+}
+
+// check reports the same error BoltDB does for the closed DB
+func (m *Memory) check() error {
+	if m.closed {
+		return bbolt.ErrDatabaseNotOpen
+	}
+	return nil
+}
+
+// memoryBucket implements Bucket for Memory.
+// The keys are kept sorted for the range scans, the stored values are never modified in place
+type memoryBucket struct {
+	values map[string][]byte
+	keys   []string
+}
+
+// newMemoryBucket creates the bucket with the optional key-value pairs
+func newMemoryBucket(pairs ...string) *memoryBucket {
+	b := &memoryBucket{values: make(map[string][]byte)}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		b.Put(pairs[i], []byte(pairs[i+1]))
+	}
+	return b
+}
+
+func (b *memoryBucket) clone() *memoryBucket {
+	c := &memoryBucket{values: make(map[string][]byte, len(b.values)), keys: make([]string, len(b.keys))}
+	for k, v := range b.values {
+		c.values[k] = v
+	}
+	copy(c.keys, b.keys)
+	return c
+}
+
+func (b *memoryBucket) Keys() ([]string, error) {
+	keys := make([]string, len(b.keys))
+	copy(keys, b.keys)
+	return keys, nil
+}
+
+func (b *memoryBucket) Get(key string) ([]byte, error) {
+	v, ok := b.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	value := make([]byte, len(v))
+	copy(value, v)
+	return value, nil
+}
+
+func (b *memoryBucket) Put(key string, val []byte) error {
+	if key == "" {
+		return bbolt.ErrKeyRequired
+	}
+	if _, ok := b.values[key]; !ok {
+		i := sort.SearchStrings(b.keys, key)
+		b.keys = append(b.keys, "")
+		copy(b.keys[i+1:], b.keys[i:])
+		b.keys[i] = key
+	}
+	// nil values are stored as empty ones, same as BoltDB does
+	value := make([]byte, len(val))
+	copy(value, val)
+	b.values[key] = value
+	return nil
+}
+
+func (b *memoryBucket) Delete(key string) error {
+	if _, ok := b.values[key]; !ok {
+		return nil
+	}
+	i := sort.SearchStrings(b.keys, key)
+	b.keys = append(b.keys[:i], b.keys[i+1:]...)
+	delete(b.values, key)
+	return nil
+}
+
+func (b *memoryBucket) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	start, end := 0, len(b.keys)
+	if from != "" {
+		start = sort.SearchStrings(b.keys, from)
+	}
+	if to != "" {
+		end = sort.SearchStrings(b.keys, to)
+	}
+
+	for i := 0; i < end-start; i++ {
+		key := b.keys[start+i]
+		if reverse {
+			key = b.keys[end-1-i]
+		}
+		value, _ := b.Get(key)
+		ok, err := fn(key, value)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryTx implements Tx for Memory.
+// The writable transaction works with the copies of the buckets
+type memoryTx struct {
+	*memoryBucket
+	source   map[string]*memoryBucket
+	buckets  map[string]*memoryBucket
+	writable bool
+}
+
+func (t *memoryTx) Put(key string, val []byte) error {
+	if !t.writable {
+		return bbolt.ErrTxNotWritable
+	}
+	return t.memoryBucket.Put(key, val)
+}
+
+func (t *memoryTx) Delete(key string) error {
+	if !t.writable {
+		return bbolt.ErrTxNotWritable
+	}
+	return t.memoryBucket.Delete(key)
+}
+
+func (t *memoryTx) Bucket(name string) (Bucket, error) {
+	b := t.bucket(name)
+	switch {
+	case b == nil:
+		return emptyBucket{}, nil
+	case !t.writable:
+		return readOnlyBucket{Bucket: b}, nil
+	}
+	return b, nil
+}
+
+func (t *memoryTx) bucket(name string) *memoryBucket {
+	if !t.writable {
+		return t.source[name]
+	}
+	if b, ok := t.buckets[name]; ok {
+		return b
+	}
+
+	b := newMemoryBucket()
+	if source, ok := t.source[name]; ok {
+		b = source.clone()
+	}
+	t.buckets[name] = b
+	return b
+}
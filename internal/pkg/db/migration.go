@@ -0,0 +1,168 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MetaBucket holds the service information about the stored data
+const MetaBucket = "meta"
+
+const schemaVersionKey = "schema_version"
+
+// migration describes a single upgrade step of the stored data.
+// It must work with the data layout of its own version only, so it never uses the current types
+type migration struct {
+	version     int
+	description string
+	// apply upgrades the data inside of the transaction and returns the list of changed keys
+	apply func(tx Tx) ([]string, error)
+}
+
+// migrations is the ordered registry of all of the schema versions
+var migrations = []migration{
+	{
+		version:     1,
+		description: "mark the initial schema version",
+		apply:       func(Tx) ([]string, error) { return nil, nil },
+	},
+	{
+		version:     2,
+		description: "move the platform to the front of the release keys",
+		apply:       platformFirstKeys,
+	},
+}
+
+// dateFirstKey matches the "date-arch" release keys of the schema version 1
+var dateFirstKey = regexp.MustCompile(`^(\d{8})-([a-z0-9_]+)$`)
+
+// platformFirstKeys renames the "date-arch" keys to "arch/date" ones,
+// so the releases of a platform are stored together in the date order
+func platformFirstKeys(tx Tx) ([]string, error) {
+	keys, err := tx.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for _, key := range keys {
+		parts := dateFirstKey.FindStringSubmatch(key)
+		if parts == nil {
+			continue
+		}
+		value, err := tx.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Put(parts[2]+"/"+parts[1], value); err != nil {
+			return nil, err
+		}
+		if err = tx.Delete(key); err != nil {
+			return nil, err
+		}
+		changes = append(changes, key)
+	}
+	return changes, nil
+}
+
+// errDryRun is used to roll back the dry-run transaction
+var errDryRun = errors.New("dry run")
+
+// MigrationReport describes the applied migration, or the pending one in the dry-run mode
+type MigrationReport struct {
+	Version     int      `json:"version"`
+	Description string   `json:"description"`
+	Changes     []string `json:"changes,omitempty"`
+}
+
+// SchemaVersion returns the latest known schema version
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate applies all of the pending migrations inside of a single transaction.
+// Nothing is saved in the dry-run mode, only the reports are returned
+func migrate(update func(fn func(tx Tx) error) error, dryRun bool) ([]MigrationReport, error) {
+	var reports []MigrationReport
+	err := update(func(tx Tx) error {
+		meta, err := tx.Bucket(MetaBucket)
+		if err != nil {
+			return err
+		}
+		current, err := getSchemaVersion(meta)
+		if err != nil {
+			return err
+		}
+		if current > SchemaVersion() {
+			return fmt.Errorf("DB schema version %d is newer than the supported one %d", current, SchemaVersion())
+		}
+
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+			changes, err := m.apply(tx)
+			if err != nil {
+				return fmt.Errorf("unable to apply migration %d (%s): %w", m.version, m.description, err)
+			}
+			reports = append(reports, MigrationReport{Version: m.version, Description: m.description, Changes: changes})
+		}
+		if len(reports) == 0 {
+			return nil
+		}
+
+		if err = meta.Put(schemaVersionKey, []byte(strconv.Itoa(SchemaVersion()))); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("unable to migrate DB: %w", err)
+	}
+
+	for _, r := range reports {
+		log.WithField("version", r.Version).WithField("changes", len(r.Changes)).WithField("dry_run", dryRun).
+			Infof("DB migration: %s", r.Description)
+	}
+	return reports, nil
+}
+
+// checkSchemaVersion fails if the DB schema version is not the latest one, so the DB can't be used without migrations
+func checkSchemaVersion(view func(fn func(tx Tx) error) error) error {
+	return view(func(tx Tx) error {
+		meta, err := tx.Bucket(MetaBucket)
+		if err != nil {
+			return err
+		}
+		current, err := getSchemaVersion(meta)
+		if err != nil {
+			return err
+		}
+		if current != SchemaVersion() {
+			return fmt.Errorf("DB schema version %d doesn't match the supported one %d", current, SchemaVersion())
+		}
+		return nil
+	})
+}
+
+func getSchemaVersion(meta Bucket) (int, error) {
+	data, err := meta.Get(schemaVersionKey)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("bad DB schema version '%s': %w", data, err)
+	}
+	return version, nil
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsRegistry(t *testing.T) {
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, "migrations must be ordered and have no gaps")
+		assert.NotEmpty(t, m.description)
+		assert.NotNil(t, m.apply)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	log.SetLevel(log.FatalLevel) // ignore DB logging for tests
+	registry := migrations
+	defer func() { migrations = registry }()
+
+	var applied []int
+	migrations = append(append([]migration{}, registry...),
+		migration{
+			version:     len(registry) + 1,
+			description: "rename the key",
+			apply: func(tx Tx) ([]string, error) {
+				applied = append(applied, len(registry)+1)
+				value, err := tx.Get("old")
+				if err != nil {
+					return nil, err
+				}
+				if err = tx.Delete("old"); err != nil {
+					return nil, err
+				}
+				return []string{"old", "new"}, tx.Put("new", value)
+			},
+		},
+		migration{
+			version:     len(registry) + 2,
+			description: "add the key",
+			apply: func(tx Tx) ([]string, error) {
+				applied = append(applied, len(registry)+2)
+				return []string{"added"}, tx.Put("added", []byte("value"))
+			},
+		},
+	)
+
+	path := filepath.Join(t.TempDir(), "bolt.db")
+	db, err := New(path, time.Second, WithoutMigrations())
+	require.NoError(t, err)
+	defer db.Close(true)
+	require.NoError(t, db.Put("old", []byte("value")))
+
+	// dry run reports everything and changes nothing
+	reports, err := db.Migrate(true)
+	require.NoError(t, err)
+	require.Len(t, reports, len(registry)+2)
+	assert.Equal(t, []string{"old", "new"}, reports[len(registry)].Changes)
+	assert.Equal(t, []string{"added"}, reports[len(registry)+1].Changes)
+	keys, err := db.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old"}, keys)
+
+	// the real run applies the migrations in order
+	applied = nil
+	reports, err = db.Migrate(false)
+	require.NoError(t, err)
+	assert.Len(t, reports, len(registry)+2)
+	assert.Equal(t, []int{len(registry) + 1, len(registry) + 2}, applied)
+	keys, err = db.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"added", "new"}, keys)
+
+	// nothing is left to do
+	reports, err = db.Migrate(false)
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+}
+
+func TestMigrateFailure(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	registry := migrations
+	defer func() { migrations = registry }()
+
+	// created with the current schema version
+	m := NewMemory()
+	migrations = append(append([]migration{}, registry...),
+		migration{
+			version:     len(registry) + 1,
+			description: "fail halfway",
+			apply: func(tx Tx) ([]string, error) {
+				if err := tx.Put("partial", []byte("value")); err != nil {
+					return nil, err
+				}
+				return nil, errors.New("failure")
+			},
+		},
+	)
+
+	_, err := m.Migrate(false)
+	require.Error(t, err)
+
+	// the transaction is rolled back
+	keys, err := m.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestMigrateNewerSchema(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	path := filepath.Join(t.TempDir(), "bolt.db")
+	db, err := New(path, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx Tx) error {
+		meta, err := tx.Bucket(MetaBucket)
+		if err != nil {
+			return err
+		}
+		return meta.Put(schemaVersionKey, []byte("1000"))
+	}))
+	require.NoError(t, db.Close(false))
+
+	_, err = New(path, time.Second)
+	assert.Error(t, err)
+}
+
+func TestPlatformFirstKeys(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	path := filepath.Join(t.TempDir(), "bolt.db")
+	db, err := New(path, time.Second, WithoutMigrations())
+	require.NoError(t, err)
+	defer db.Close(true)
+	for _, key := range []string{"20200101-arm", "20200102-x86_64", "arm64/20200101", "bad-key"} {
+		require.NoError(t, db.Put(key, []byte(key)))
+	}
+
+	reports, err := db.Migrate(false)
+	require.NoError(t, err)
+	require.Len(t, reports, len(migrations))
+	assert.Equal(t, []string{"20200101-arm", "20200102-x86_64"}, reports[1].Changes)
+
+	keys, err := db.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/20200101", "arm64/20200101", "bad-key", "x86_64/20200102"}, keys)
+	value, err := db.Get("arm/20200101")
+	require.NoError(t, err)
+	assert.Equal(t, "20200101-arm", string(value))
+}
@@ -0,0 +1,38 @@
+package db
+
+// settings holds the optional DB parameters
+type settings struct {
+	skipMigrations bool
+	readOnly       bool
+}
+
+// Option serves as the DB configuration
+type Option func(*settings) error
+
+// WithoutMigrations disables running the pending migrations on DB creation.
+// It's up to the caller to run them with the Migrate method
+func WithoutMigrations() Option {
+	return func(s *settings) error {
+		s.skipMigrations = true
+		return nil
+	}
+}
+
+// ReadOnly opens the existing DB for reading only, so the writes fail.
+// The migrations are not applied, and the DB must have the latest schema version
+func ReadOnly() Option {
+	return func(s *settings) error {
+		s.readOnly = true
+		return nil
+	}
+}
+
+func newSettings(opts []Option) (*settings, error) {
+	s := &settings{}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
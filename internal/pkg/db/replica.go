@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrReadOnly is returned by the writes to the Replica
+var ErrReadOnly = errors.New("storage is read-only")
+
+// ReplicaStats describes the freshness of the Replica data
+type ReplicaStats struct {
+	// SnapshotTime is the time the loaded snapshot was published
+	SnapshotTime time.Time `json:"snapshot_time"`
+	LoadedAt     time.Time `json:"loaded_at"`
+	Reloads      uint64    `json:"reloads"`
+	// AgeSeconds is the time passed since the snapshot was published
+	AgeSeconds float64 `json:"age_seconds"`
+}
+
+// Replica is the read-only Storage, which serves the BoltDB snapshot published by the writer with PublishSnapshot.
+// The snapshot is reopened by Reload when the file is replaced, all of the writes fail with ErrReadOnly
+type Replica struct {
+	path    string
+	timeout time.Duration
+
+	mtx   sync.RWMutex
+	db    *DB
+	info  os.FileInfo
+	stats ReplicaStats
+}
+
+// NewReplica creates new instance of Replica, the snapshot file must exist
+func NewReplica(path string, timeout time.Duration) (*Replica, error) {
+	r := &Replica{path: path, timeout: timeout}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reopens the snapshot if the file was replaced since it was loaded, reporting if it was.
+// The current snapshot is kept on failure
+func (r *Replica) Reload() (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, fmt.Errorf("unable to reload snapshot: %w", err)
+	}
+	// the published snapshot is always the new file, which can't reuse the inode of the open one
+	r.mtx.RLock()
+	changed := r.info == nil || !os.SameFile(info, r.info) || !info.ModTime().Equal(r.info.ModTime())
+	r.mtx.RUnlock()
+	if !changed {
+		return false, nil
+	}
+
+	db, err := New(r.path, r.timeout, ReadOnly())
+	if err != nil {
+		return false, fmt.Errorf("unable to reload snapshot: %w", err)
+	}
+	r.mtx.Lock()
+	previous := r.db
+	r.db, r.info = db, info
+	r.stats.SnapshotTime = info.ModTime()
+	r.stats.LoadedAt = time.Now()
+	r.stats.Reloads++
+	r.mtx.Unlock()
+
+	// the readers of the previous snapshot are done, as the lock was taken
+	if previous != nil {
+		if err = previous.Close(false); err != nil {
+			log.WithError(err).Error("Unable to close the previous snapshot")
+		}
+	}
+	log.WithField("snapshot_time", info.ModTime()).Info("Loaded the DB snapshot")
+	return true, nil
+}
+
+// Watch reloads the snapshot with the interval until the context is canceled
+func (r *Replica) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Context canceled, exiting snapshot watcher")
+			return
+		case <-ticker.C:
+			if _, err := r.Reload(); err != nil {
+				log.WithError(err).Error("Unable to reload the DB snapshot")
+			}
+		}
+	}
+}
+
+// Stats returns the freshness of the loaded snapshot
+func (r *Replica) Stats() ReplicaStats {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	stats := r.stats
+	stats.AgeSeconds = time.Since(stats.SnapshotTime).Seconds()
+	return stats
+}
+
+// Close closes the loaded snapshot, the file is never removed as it belongs to the writer
+func (r *Replica) Close(bool) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.db.Close(false)
+}
+
+// Keys returns a list of available keys, sorted alphabetically
+func (r *Replica) Keys() ([]string, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.db.Keys()
+}
+
+// Get acquires value by provided key
+func (r *Replica) Get(key string) ([]byte, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.db.Get(key)
+}
+
+// GetMultipleBySuffix returns keys and values, for which the key contains the suffix, sorted by key
+func (r *Replica) GetMultipleBySuffix(suffix string) ([]string, [][]byte, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.db.GetMultipleBySuffix(suffix)
+}
+
+// Put fails, as the Replica is read-only
+func (r *Replica) Put(key string, _ []byte) error {
+	return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, ErrReadOnly)
+}
+
+// Delete fails, as the Replica is read-only
+func (r *Replica) Delete(key string) error {
+	return fmt.Errorf("unable to delete value for key '%s' from DB: %w", key, ErrReadOnly)
+}
+
+// Purge fails, as the Replica is read-only
+func (r *Replica) Purge() error {
+	return fmt.Errorf("unable to purge global bucket from DB: %w", ErrReadOnly)
+}
+
+// Migrate fails, as the Replica is read-only. The snapshot is checked to have the latest schema version on load
+func (r *Replica) Migrate(bool) ([]MigrationReport, error) {
+	return nil, fmt.Errorf("unable to migrate DB: %w", ErrReadOnly)
+}
+
+// Update fails, as the Replica is read-only
+func (r *Replica) Update(func(tx Tx) error) error {
+	return ErrReadOnly
+}
+
+// View runs the function inside of the read-only transaction of the loaded snapshot
+func (r *Replica) View(fn func(tx Tx) error) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.db.View(fn)
+}
+
+// Scan calls the function for the keys in the [from, to) range of the global bucket
+func (r *Replica) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.db.Scan(from, to, reverse, fn)
+}
+
+// Backup runs the function with the snapshot of the loaded snapshot
+func (r *Replica) Backup(fn func(s Snapshot) error) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.db.Backup(fn)
+}
+
+// Compact fails, as the Replica is read-only
+func (r *Replica) Compact() error {
+	return fmt.Errorf("unable to compact DB: %w", ErrReadOnly)
+}
+
+// PublishSnapshot writes the snapshot of the storage to the path for the replicas.
+// The file is replaced atomically, so the replicas never see the partial snapshot
+func PublishSnapshot(s Storage, path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to publish snapshot: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	err = s.Backup(func(snapshot Snapshot) error {
+		return WriteSnapshot(f, snapshot, false)
+	})
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		// the replicas may run as the other user
+		err = f.Chmod(openMode)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to publish snapshot: %w", err)
+	}
+	return nil
+}
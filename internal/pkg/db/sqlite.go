@@ -0,0 +1,406 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+
+	// registers the pure-Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+const (
+	sqliteDriverName  = "sqlite"
+	sqliteDSNTemplate = "%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(wal)"
+
+	// every bucket is stored in the table with the same name to make both layouts look the same
+	sqliteCreateTable = `CREATE TABLE IF NOT EXISTS %s (key TEXT PRIMARY KEY, value TEXT NOT NULL)`
+	sqliteTableExists = `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	sqliteSelectKeys  = `SELECT key FROM %s ORDER BY key`
+	sqliteSelectValue = `SELECT value FROM %s WHERE key = ?`
+	sqliteSelectByEnd = `SELECT key, value FROM %s WHERE substr(key, length(key) - length(?1) + 1) = ?1 ORDER BY key`
+	sqliteUpsert      = `INSERT INTO %s (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`
+	sqliteDelete      = `DELETE FROM %s WHERE key = ?`
+	sqlitePurge       = `DELETE FROM %s`
+	sqliteVacuum      = `VACUUM`
+	sqliteVacuumInto  = `VACUUM INTO ?`
+	sqliteSelectRange = `SELECT key, value FROM %s WHERE (?1 = '' OR key >= ?1) AND (?2 = '' OR key < ?2) AND (?3 = '' OR key > ?3) ORDER BY key %s LIMIT %d`
+
+	// sqliteScanPage limits the number of rows fetched at once by Scan
+	sqliteScanPage = 64
+)
+
+// sqliteTableName limits the bucket names, as they are used in the queries as is
+var sqliteTableName = regexp.MustCompile(`^[a-z][a-z_]*$`)
+
+// SQLite describes local SQLite database with the same semantics as DB.
+// The values are stored as text, so the JSON records can be queried with the SQLite JSON functions
+type SQLite struct {
+	db      *sql.DB
+	path    string
+	timeout time.Duration
+}
+
+// NewSQLite creates new instance of SQLite and applies the pending migrations
+func NewSQLite(path string, timeout time.Duration, opts ...Option) (*SQLite, error) {
+	settings, err := newSettings(opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply DB options: %w", err)
+	}
+	if settings.readOnly {
+		return nil, errors.New("read-only mode is not supported by SQLite DB")
+	}
+
+	// open connection to the DB
+	log.WithField("path", path).WithField("timeout", timeout).Debug("Creating SQLite DB connection")
+	s, err := sql.Open(sqliteDriverName, fmt.Sprintf(sqliteDSNTemplate, path, timeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open DB: %w", err)
+	}
+	// SQLite serializes the writes anyway, and in-memory DB exists only within a single connection
+	s.SetMaxOpenConns(1)
+
+	// create global table if it doesn't exist yet
+	log.WithField("table", string(bucketName)).Debug("Setting the default table")
+	if _, err = s.Exec(fmt.Sprintf(sqliteCreateTable, bucketName)); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("unable to create global table: %w", err)
+	}
+
+	// upgrade the stored data
+	db := &SQLite{db: s, path: path, timeout: timeout}
+	if !settings.skipMigrations {
+		if _, err = db.Migrate(false); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	// return the DB
+	log.Debug("SQLite DB initiated")
+	return db, nil
+}
+
+// Close closes the DB
+func (s *SQLite) Close(delete bool) error {
+	log.Debug("Closing the SQLite DB")
+	done := make(chan error)
+	go func() {
+		done <- s.db.Close()
+		log.Debug("SQLite DB closed OK")
+		close(done)
+	}()
+	timer := time.NewTimer(s.timeout)
+	if delete && s.path != MemoryPath {
+		defer func() {
+			for _, suffix := range []string{"", "-wal", "-shm"} {
+				os.Remove(s.path + suffix)
+			}
+		}()
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("unable to close DB: %w", err)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("unable to close DB: %w", bbolt.ErrTimeout)
+	}
+}
+
+// Keys returns a list of available keys in the global table, sorted alphabetically
+func (s *SQLite) Keys() ([]string, error) {
+	log.Debug("Getting the list of DB current keys")
+	keys, err := sqliteBucket{q: s.db, table: string(bucketName)}.Keys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the list of keys from DB: %w", err)
+	}
+	log.Debug("Got the keys")
+	return keys, nil
+}
+
+// Get acquires value from DB by provided key
+func (s *SQLite) Get(key string) ([]byte, error) {
+	log.WithField("key", key).Debug("Getting value from DB")
+	value, err := sqliteBucket{q: s.db, table: string(bucketName)}.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+	}
+	log.WithField("key", key).Debug("Got the value")
+	return value, nil
+}
+
+// GetMultipleBySuffix returns keys and values, for which the key contains the suffix
+// It returns all keys and values from the table if the suffix is empty
+func (s *SQLite) GetMultipleBySuffix(suffix string) ([]string, [][]byte, error) {
+	log.WithField("suffix", suffix).Debug("Getting values from DB by suffix")
+	rows, err := s.db.Query(fmt.Sprintf(sqliteSelectByEnd, bucketName), suffix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get values for suffix '%s' from DB: %w", suffix, err)
+	}
+	defer rows.Close()
+
+	var (
+		keys   []string
+		values [][]byte
+	)
+	for rows.Next() {
+		var (
+			key   string
+			value []byte
+		)
+		if err = rows.Scan(&key, &value); err != nil {
+			return nil, nil, fmt.Errorf("unable to get values for suffix '%s' from DB: %w", suffix, err)
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("unable to get values for suffix '%s' from DB: %w", suffix, err)
+	}
+	log.WithField("suffix", suffix).Debug("Got the values")
+	return keys, values, nil
+}
+
+// Put sets/updates the value in DB by provided key
+func (s *SQLite) Put(key string, val []byte) error {
+	log.WithField("key", key).Debug("Saving the value to DB")
+	if err := (sqliteBucket{q: s.db, table: string(bucketName)}).Put(key, val); err != nil {
+		return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+	}
+	log.WithField("key", key).Debug("Saved successfully")
+	return nil
+}
+
+// Delete removes the value from DB by provided key
+func (s *SQLite) Delete(key string) error {
+	log.WithField("key", key).Debug("Deleting from DB")
+	if err := (sqliteBucket{q: s.db, table: string(bucketName)}).Delete(key); err != nil {
+		return fmt.Errorf("unable to delete value for key '%s' from DB: %w", key, err)
+	}
+	log.WithField("key", key).Debug("Deleted successfully")
+	return nil
+}
+
+// Purge removes all of the values from the global table
+func (s *SQLite) Purge() error {
+	log.Debug("Purging the SQLite DB")
+	if _, err := s.db.Exec(fmt.Sprintf(sqlitePurge, bucketName)); err != nil {
+		return fmt.Errorf("unable to purge global table from DB: %w", err)
+	}
+	return nil
+}
+
+// Migrate applies the pending migrations inside of a single transaction.
+// In the dry-run mode the changes are rolled back and only reported
+func (s *SQLite) Migrate(dryRun bool) ([]MigrationReport, error) {
+	return migrate(s.Update, dryRun)
+}
+
+// Compact rebuilds the DB file to reclaim the space left by the removed values
+func (s *SQLite) Compact() error {
+	log.Debug("Compacting the SQLite DB")
+	if _, err := s.db.Exec(sqliteVacuum); err != nil {
+		return fmt.Errorf("unable to compact DB: %w", err)
+	}
+	return nil
+}
+
+// Scan calls the function for the keys in the [from, to) range of the global table
+func (s *SQLite) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	return scanStorage(s.View, from, to, reverse, fn)
+}
+
+// Update runs the function inside of the read-write transaction
+func (s *SQLite) Update(fn func(tx Tx) error) error {
+	return s.runTx(true, fn)
+}
+
+// View runs the function inside of the read-only transaction
+func (s *SQLite) View(fn func(tx Tx) error) error {
+	return s.runTx(false, fn)
+}
+
+func (s *SQLite) runTx(writable bool, fn func(tx Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = fn(sqliteTx{sqliteBucket: sqliteBucket{q: tx, table: string(bucketName)}, tx: tx, writable: writable}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteQuerier is implemented by both sql.DB and sql.Tx
+type sqliteQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// sqliteBucket implements Bucket for SQLite
+type sqliteBucket struct {
+	q     sqliteQuerier
+	table string
+}
+
+func (b sqliteBucket) Keys() ([]string, error) {
+	rows, err := b.q.Query(fmt.Sprintf(sqliteSelectKeys, b.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (b sqliteBucket) Get(key string) ([]byte, error) {
+	var value []byte
+	err := b.q.QueryRow(fmt.Sprintf(sqliteSelectValue, b.table), key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+func (b sqliteBucket) Put(key string, val []byte) error {
+	if key == "" {
+		return bbolt.ErrKeyRequired
+	}
+	_, err := b.q.Exec(fmt.Sprintf(sqliteUpsert, b.table), key, string(val))
+	return err
+}
+
+func (b sqliteBucket) Delete(key string) error {
+	_, err := b.q.Exec(fmt.Sprintf(sqliteDelete, b.table), key)
+	return err
+}
+
+// Scan fetches the range page by page, so the function is never called while the rows are open
+func (b sqliteBucket) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	order := "ASC"
+	if reverse {
+		order = "DESC"
+	}
+	query := fmt.Sprintf(sqliteSelectRange, b.table, order, sqliteScanPage)
+
+	after := ""
+	for {
+		keys, values, err := b.page(query, from, to, after)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			ok, err := fn(keys[i], values[i])
+			if err != nil || !ok {
+				return err
+			}
+		}
+		if len(keys) < sqliteScanPage {
+			return nil
+		}
+
+		// continue right after the last returned key
+		if reverse {
+			to = keys[len(keys)-1]
+		} else {
+			after = keys[len(keys)-1]
+		}
+	}
+}
+
+func (b sqliteBucket) page(query, from, to, after string) ([]string, [][]byte, error) {
+	rows, err := b.q.Query(query, from, to, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var (
+		keys   []string
+		values [][]byte
+	)
+	for rows.Next() {
+		var (
+			key   string
+			value []byte
+		)
+		if err = rows.Scan(&key, &value); err != nil {
+			return nil, nil, err
+		}
+		if value == nil {
+			value = []byte{}
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, rows.Err()
+}
+
+// sqliteTx implements Tx for SQLite
+type sqliteTx struct {
+	sqliteBucket
+	tx       *sql.Tx
+	writable bool
+}
+
+func (t sqliteTx) Put(key string, val []byte) error {
+	if !t.writable {
+		return bbolt.ErrTxNotWritable
+	}
+	return t.sqliteBucket.Put(key, val)
+}
+
+func (t sqliteTx) Delete(key string) error {
+	if !t.writable {
+		return bbolt.ErrTxNotWritable
+	}
+	return t.sqliteBucket.Delete(key)
+}
+
+func (t sqliteTx) Bucket(name string) (Bucket, error) {
+	if !sqliteTableName.MatchString(name) {
+		return nil, fmt.Errorf("bad bucket name '%s'", name)
+	}
+
+	if t.writable {
+		if _, err := t.tx.Exec(fmt.Sprintf(sqliteCreateTable, name)); err != nil {
+			return nil, fmt.Errorf("unable to create bucket '%s': %w", name, err)
+		}
+		return sqliteBucket{q: t.tx, table: name}, nil
+	}
+
+	var count int
+	if err := t.tx.QueryRow(sqliteTableExists, name).Scan(&count); err != nil {
+		return nil, fmt.Errorf("unable to check bucket '%s': %w", name, err)
+	}
+	if count == 0 {
+		return emptyBucket{}, nil
+	}
+	return readOnlyBucket{Bucket: sqliteBucket{q: t.tx, table: name}}, nil
+}
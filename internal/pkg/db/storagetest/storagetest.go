@@ -0,0 +1,595 @@
+// Package storagetest provides the conformance suite for the Storage implementations.
+// Every backend is expected to pass it, so the callers can rely on the same behaviour
+// regardless of the configured DB driver.
+package storagetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const concurrency = 16
+
+// Factory creates new empty Storage for every test case.
+// It is up to the factory to clean up the created Storage
+type Factory func(t *testing.T) db.Storage
+
+// Run launches the conformance suite against the storage created by the factory
+func Run(t *testing.T, newStorage Factory) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, s db.Storage)
+	}{
+		{"EmptyStorage", testEmptyStorage},
+		{"PutGet", testPutGet},
+		{"PutOverwrites", testPutOverwrites},
+		{"PutEmptyKey", testPutEmptyKey},
+		{"NilValue", testNilValue},
+		{"MissingKey", testMissingKey},
+		{"ValuesAreCopied", testValuesAreCopied},
+		{"KeysSorted", testKeysSorted},
+		{"SuffixMatching", testSuffixMatching},
+		{"Delete", testDelete},
+		{"Scan", testScan},
+		{"ScanStop", testScanStop},
+		{"ScanLarge", testScanLarge},
+		{"Purge", testPurge},
+		{"Concurrency", testConcurrency},
+		{"Migrated", testMigrated},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+		{"TxView", testTxView},
+		{"TxBuckets", testTxBuckets},
+		{"TxConcurrency", testTxConcurrency},
+		{"Backup", testBackup},
+		{"Compact", testCompact},
+		{"Closed", testClosed},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, newStorage(t))
+		})
+	}
+}
+
+func testEmptyStorage(t *testing.T, s db.Storage) {
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keys, values, err := s.GetMultipleBySuffix("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.Empty(t, values)
+}
+
+func testPutGet(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+
+	value, err := s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func testPutOverwrites(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("old")))
+	require.NoError(t, s.Put("20200101-arm", []byte("new")))
+
+	value, err := s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), value)
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200101-arm"}, keys)
+}
+
+func testPutEmptyKey(t *testing.T, s db.Storage) {
+	assert.Error(t, s.Put("", []byte("value")))
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+// nil value is stored as the empty one and is distinguishable from the missing key
+func testNilValue(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", nil))
+
+	value, err := s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.NotNil(t, value)
+	assert.Empty(t, value)
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200101-arm"}, keys)
+}
+
+func testMissingKey(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+
+	for _, key := range []string{"", "20200101", "20200101-ar", "20200101-arm64", "20200102-arm"} {
+		value, err := s.Get(key)
+		assert.Nil(t, value, key)
+		assert.ErrorIs(t, err, db.ErrNotFound, key)
+		assert.False(t, errors.Is(err, db.ErrNilValue), key)
+	}
+}
+
+func testValuesAreCopied(t *testing.T, s db.Storage) {
+	input := []byte("value")
+	require.NoError(t, s.Put("20200101-arm", input))
+	input[0] = 'X'
+
+	value, err := s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	value[0] = 'X'
+
+	value, err = s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func testKeysSorted(t *testing.T, s db.Storage) {
+	want := []string{"20190101-x86", "20200101-arm", "20200101-arm64", "20200101-x86_64", "20200102-arm"}
+	for _, i := range []int{3, 0, 4, 2, 1} {
+		require.NoError(t, s.Put(want[i], []byte(want[i])))
+	}
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, want, keys)
+	assert.True(t, sort.StringsAreSorted(keys))
+}
+
+func testSuffixMatching(t *testing.T, s db.Storage) {
+	all := []string{"20200101-arm", "20200101-arm64", "20200101-x86", "20200101-x86_64", "20200102-arm"}
+	for _, key := range all {
+		require.NoError(t, s.Put(key, []byte("v"+key)))
+	}
+
+	cases := map[string][]string{
+		"":       all,
+		"arm":    {"20200101-arm", "20200102-arm"},
+		"-arm":   {"20200101-arm", "20200102-arm"},
+		"arm64":  {"20200101-arm64"},
+		"x86":    {"20200101-x86"},
+		"x86_64": {"20200101-x86_64"},
+		"_64":    {"20200101-x86_64"},
+		"mips":   nil,
+	}
+	for suffix, want := range cases {
+		keys, values, err := s.GetMultipleBySuffix(suffix)
+		require.NoError(t, err, suffix)
+		require.Len(t, keys, len(want), suffix)
+		require.Len(t, values, len(want), suffix)
+		for i := range want {
+			assert.Equal(t, want[i], keys[i], suffix)
+			assert.Equal(t, []byte("v"+want[i]), values[i], suffix)
+		}
+	}
+}
+
+func scanKeys(t *testing.T, s db.Storage, from, to string, reverse bool) []string {
+	keys := []string{}
+	err := s.Scan(from, to, reverse, func(key string, value []byte) (bool, error) {
+		assert.Equal(t, "v"+key, string(value))
+		keys = append(keys, key)
+		return true, nil
+	})
+	require.NoError(t, err)
+	return keys
+}
+
+func testScan(t *testing.T, s db.Storage) {
+	all := []string{"arm/20200101", "arm/20200102", "arm/20200105", "arm64/20200101", "x86/20200101"}
+	for _, i := range []int{4, 2, 0, 3, 1} {
+		require.NoError(t, s.Put(all[i], []byte("v"+all[i])))
+	}
+
+	cases := []struct {
+		from, to string
+		want     []string
+	}{
+		{"", "", all},
+		{"arm/", "arm0", all[:3]},
+		{"arm/20200102", "arm0", all[1:3]},
+		{"arm/20200103", "arm0", all[2:3]},
+		{"arm/", "arm/20200105", all[:2]},
+		{"arm/", "arm/20200104", all[:2]},
+		{"arm64/", "", all[3:]},
+		{"", "arm64/", all[:3]},
+		{"arm/20200106", "arm0", []string{}},
+		{"mips/", "mips0", []string{}},
+		{"x86/20200101", "x86/20200101", []string{}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, scanKeys(t, s, c.from, c.to, false), "%s - %s", c.from, c.to)
+
+		reversed := make([]string, 0, len(c.want))
+		for i := len(c.want) - 1; i >= 0; i-- {
+			reversed = append(reversed, c.want[i])
+		}
+		assert.Equal(t, reversed, scanKeys(t, s, c.from, c.to, true), "reverse %s - %s", c.from, c.to)
+	}
+}
+
+func testScanStop(t *testing.T, s db.Storage) {
+	for _, key := range []string{"arm/20200101", "arm/20200102", "arm/20200103"} {
+		require.NoError(t, s.Put(key, []byte(key)))
+	}
+
+	// the latest key only
+	var keys []string
+	err := s.Scan("arm/", "arm0", true, func(key string, _ []byte) (bool, error) {
+		keys = append(keys, key)
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/20200103"}, keys)
+
+	// the error stops the scan and is returned
+	keys = nil
+	errTest := errors.New("test")
+	err = s.Scan("", "", false, func(key string, _ []byte) (bool, error) {
+		keys = append(keys, key)
+		return true, errTest
+	})
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, []string{"arm/20200101"}, keys)
+}
+
+// the range is bigger than any internal page of the implementations
+func testScanLarge(t *testing.T, s db.Storage) {
+	var want []string
+	err := s.Update(func(tx db.Tx) error {
+		for i := 0; i < 300; i++ {
+			key := fmt.Sprintf("arm/%08d", i)
+			want = append(want, key)
+			if err := tx.Put(key, []byte("v"+key)); err != nil {
+				return err
+			}
+		}
+		return tx.Put("x86/00000000", []byte("vx86/00000000"))
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, want, scanKeys(t, s, "arm/", "arm0", false))
+	got := scanKeys(t, s, "arm/", "arm0", true)
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i], got[len(got)-1-i])
+	}
+}
+
+func testDelete(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+	require.NoError(t, s.Put("20200102-arm", []byte("value")))
+
+	require.NoError(t, s.Delete("20200101-arm"))
+	_, err := s.Get("20200101-arm")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// deleting the missing key is not an error
+	assert.NoError(t, s.Delete("20200101-arm"))
+	assert.NoError(t, s.Delete("20200103-arm"))
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200102-arm"}, keys)
+}
+
+// the storage stays usable after Purge
+func testPurge(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+	require.NoError(t, s.Put("20200102-arm", []byte("value")))
+
+	require.NoError(t, s.Purge())
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	_, err = s.Get("20200101-arm")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// purging the empty storage is not an error
+	require.NoError(t, s.Purge())
+
+	require.NoError(t, s.Put("20200103-arm", []byte("value")))
+	keys, err = s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200103-arm"}, keys)
+}
+
+func testConcurrency(t *testing.T, s db.Storage) {
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrency*3)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("202001%02d-arm", i)
+			if err := s.Put(key, []byte(key)); err != nil {
+				errs <- err
+				return
+			}
+			value, err := s.Get(key)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(value) != key {
+				errs <- fmt.Errorf("got value '%s' for key '%s'", value, key)
+			}
+			if _, _, err = s.GetMultipleBySuffix("arm"); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Len(t, keys, concurrency)
+}
+
+// new storage has the latest schema version, so there is nothing to migrate
+func testMigrated(t *testing.T, s db.Storage) {
+	for _, dryRun := range []bool{true, false} {
+		reports, err := s.Migrate(dryRun)
+		require.NoError(t, err)
+		assert.Empty(t, reports)
+	}
+}
+
+func testTxCommit(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("old")))
+	require.NoError(t, s.Put("20200102-arm", []byte("value")))
+
+	err := s.Update(func(tx db.Tx) error {
+		if err := tx.Put("20200101-arm", []byte("new")); err != nil {
+			return err
+		}
+		if err := tx.Put("20200101-arm64", []byte("value")); err != nil {
+			return err
+		}
+		if err := tx.Delete("20200102-arm"); err != nil {
+			return err
+		}
+
+		// the transaction sees its own changes
+		value, err := tx.Get("20200101-arm")
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []byte("new"), value)
+		_, err = tx.Get("20200102-arm")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		keys, err := tx.Keys()
+		assert.Equal(t, []string{"20200101-arm", "20200101-arm64"}, keys)
+		if err != nil {
+			return err
+		}
+		keys = nil
+		err = tx.Scan("20200101", "20200102", true, func(key string, _ []byte) (bool, error) {
+			keys = append(keys, key)
+			return true, nil
+		})
+		assert.Equal(t, []string{"20200101-arm64", "20200101-arm"}, keys)
+		return err
+	})
+	require.NoError(t, err)
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200101-arm", "20200101-arm64"}, keys)
+	value, err := s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new"), value)
+}
+
+func testTxRollback(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("old")))
+
+	errTest := errors.New("test")
+	err := s.Update(func(tx db.Tx) error {
+		if err := tx.Put("20200101-arm", []byte("new")); err != nil {
+			return err
+		}
+		if err := tx.Put("20200102-arm", []byte("value")); err != nil {
+			return err
+		}
+		b, err := tx.Bucket("test")
+		if err != nil {
+			return err
+		}
+		if err = b.Put("key", []byte("value")); err != nil {
+			return err
+		}
+		return fmt.Errorf("failure: %w", errTest)
+	})
+	assert.ErrorIs(t, err, errTest)
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200101-arm"}, keys)
+	value, err := s.Get("20200101-arm")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("old"), value)
+
+	err = s.View(func(tx db.Tx) error {
+		b, err := tx.Bucket("test")
+		if err != nil {
+			return err
+		}
+		_, err = b.Get("key")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func testTxView(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+
+	err := s.View(func(tx db.Tx) error {
+		value, err := tx.Get("20200101-arm")
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, []byte("value"), value)
+
+		// the writes are forbidden
+		assert.Error(t, tx.Put("20200102-arm", []byte("value")))
+		assert.Error(t, tx.Delete("20200101-arm"))
+
+		// the missing bucket is empty
+		b, err := tx.Bucket("missing")
+		if err != nil {
+			return err
+		}
+		keys, err := b.Keys()
+		assert.Empty(t, keys)
+		_, err = b.Get("key")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		assert.Error(t, b.Put("key", []byte("value")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20200101-arm"}, keys)
+}
+
+// the named buckets are separated from the global one and survive Purge
+func testTxBuckets(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("key", []byte("global")))
+	err := s.Update(func(tx db.Tx) error {
+		b, err := tx.Bucket("test")
+		if err != nil {
+			return err
+		}
+		return b.Put("key", []byte("test"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Purge())
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	err = s.View(func(tx db.Tx) error {
+		b, err := tx.Bucket("test")
+		if err != nil {
+			return err
+		}
+		value, err := b.Get("key")
+		assert.Equal(t, []byte("test"), value)
+		return err
+	})
+	require.NoError(t, err)
+
+	// the schema version is kept as well
+	reports, err := s.Migrate(false)
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+}
+
+// concurrent read-modify-write transactions never lose the updates
+func testTxConcurrency(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("counter", []byte("0")))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Update(func(tx db.Tx) error {
+				value, err := tx.Get("counter")
+				if err != nil {
+					return err
+				}
+				var counter int
+				if _, err = fmt.Sscan(string(value), &counter); err != nil {
+					return err
+				}
+				return tx.Put("counter", []byte(fmt.Sprint(counter+1)))
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	value, err := s.Get("counter")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(concurrency), string(value))
+}
+
+func testBackup(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+
+	err := s.Backup(func(snapshot db.Snapshot) error {
+		var first, second bytes.Buffer
+		require.NoError(t, db.WriteSnapshot(&first, snapshot, false))
+		require.NoError(t, db.WriteSnapshot(&second, snapshot, false))
+		assert.Equal(t, snapshot.Size(), int64(first.Len()))
+		assert.Equal(t, first.Bytes(), second.Bytes(), "snapshot must not change")
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the storage is still usable
+	require.NoError(t, s.Put("20200102-arm", []byte("value")))
+	assert.ErrorIs(t, s.Backup(func(db.Snapshot) error { return assert.AnError }), assert.AnError)
+}
+
+func testCompact(t *testing.T, s db.Storage) {
+	for i := 0; i < 100; i++ {
+		require.NoError(t, s.Put(fmt.Sprintf("key%03d", i), bytes.Repeat([]byte("value"), 100)))
+	}
+	for i := 0; i < 99; i++ {
+		require.NoError(t, s.Delete(fmt.Sprintf("key%03d", i)))
+	}
+	require.NoError(t, s.Compact())
+
+	// the data is kept and the storage is still usable
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key099"}, keys)
+	require.NoError(t, s.Put("key100", []byte("value")))
+	value, err := s.Get("key100")
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(value))
+}
+
+func testClosed(t *testing.T, s db.Storage) {
+	require.NoError(t, s.Put("20200101-arm", []byte("value")))
+	require.NoError(t, s.Close(false))
+
+	_, err := s.Keys()
+	assert.Error(t, err)
+	_, err = s.Get("20200101-arm")
+	assert.Error(t, err)
+	assert.Error(t, s.Put("20200102-arm", []byte("value")))
+}
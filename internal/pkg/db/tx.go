@@ -0,0 +1,164 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"go.etcd.io/bbolt"
+)
+
+// emptyBucket represents the missing bucket in the read-only transaction
+type emptyBucket struct{}
+
+func (emptyBucket) Keys() ([]string, error)    { return nil, nil }
+func (emptyBucket) Get(string) ([]byte, error) { return nil, ErrNotFound }
+func (emptyBucket) Put(string, []byte) error   { return bbolt.ErrTxNotWritable }
+func (emptyBucket) Delete(string) error        { return bbolt.ErrTxNotWritable }
+
+func (emptyBucket) Scan(string, string, bool, ScanFunc) error { return nil }
+
+// scanStorage runs the global bucket scan inside of the read-only transaction of the storage
+func scanStorage(view func(fn func(tx Tx) error) error, from, to string, reverse bool, fn ScanFunc) error {
+	err := view(func(tx Tx) error {
+		return tx.Scan(from, to, reverse, fn)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to scan keys from '%s' to '%s' in DB: %w", from, to, err)
+	}
+	return nil
+}
+
+// readOnlyBucket guards the bucket in the read-only transaction
+type readOnlyBucket struct {
+	Bucket
+}
+
+func (readOnlyBucket) Put(string, []byte) error { return bbolt.ErrTxNotWritable }
+func (readOnlyBucket) Delete(string) error      { return bbolt.ErrTxNotWritable }
+
+// boltBucket implements Bucket for BoltDB
+type boltBucket struct {
+	b *bbolt.Bucket
+}
+
+func (b boltBucket) Keys() ([]string, error) {
+	var keys []string
+	err := b.b.ForEach(func(k, v []byte) error {
+		if v != nil {
+			keys = append(keys, string(k))
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (b boltBucket) Get(key string) ([]byte, error) {
+	k, v := b.b.Cursor().Seek([]byte(key))
+	if k == nil || string(k) != key {
+		return nil, ErrNotFound
+	} else if v == nil {
+		return nil, ErrNilValue
+	}
+	// the value is valid only during the transaction
+	value := make([]byte, len(v))
+	copy(value, v)
+	return value, nil
+}
+
+func (b boltBucket) Put(key string, val []byte) error {
+	return b.b.Put([]byte(key), val)
+}
+
+func (b boltBucket) Delete(key string) error {
+	return b.b.Delete([]byte(key))
+}
+
+func (b boltBucket) Scan(from, to string, reverse bool, fn ScanFunc) error {
+	c := b.b.Cursor()
+	var k, v []byte
+	switch {
+	case !reverse && from == "":
+		k, v = c.First()
+	case !reverse:
+		k, v = c.Seek([]byte(from))
+	case to == "":
+		k, v = c.Last()
+	default:
+		// Seek stops at the first key not less than the bound, so the previous one is the last in range
+		if k, _ = c.Seek([]byte(to)); k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+	}
+
+	for ; k != nil && inRange(string(k), from, to); k, v = next(c, reverse) {
+		if v == nil {
+			continue
+		}
+		value := make([]byte, len(v))
+		copy(value, v)
+		ok, err := fn(string(k), value)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func next(c *bbolt.Cursor, reverse bool) ([]byte, []byte) {
+	if reverse {
+		return c.Prev()
+	}
+	return c.Next()
+}
+
+// inRange checks if the key is inside of the [from, to) range with the optional bounds
+func inRange(key, from, to string) bool {
+	return (from == "" || key >= from) && (to == "" || key < to)
+}
+
+// boltTx implements Tx for BoltDB
+type boltTx struct {
+	boltBucket
+	tx *bbolt.Tx
+}
+
+func (t boltTx) Bucket(name string) (Bucket, error) {
+	if !t.tx.Writable() {
+		b := t.tx.Bucket([]byte(name))
+		if b == nil {
+			return emptyBucket{}, nil
+		}
+		return boltBucket{b: b}, nil
+	}
+
+	b, err := t.tx.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create bucket '%s': %w", name, err)
+	}
+	return boltBucket{b: b}, nil
+}
+
+// Update runs the function inside of the read-write transaction
+func (db *DB) Update(fn func(tx Tx) error) error {
+	return db.b.Update(func(tx *bbolt.Tx) error {
+		return db.runTx(tx, fn)
+	})
+}
+
+// View runs the function inside of the read-only transaction
+func (db *DB) View(fn func(tx Tx) error) error {
+	return db.b.View(func(tx *bbolt.Tx) error {
+		return db.runTx(tx, fn)
+	})
+}
+
+func (db *DB) runTx(tx *bbolt.Tx, fn func(tx Tx) error) error {
+	b := tx.Bucket(bucketName)
+	if b == nil {
+		return bbolt.ErrBucketNotFound
+	}
+	return fn(boltTx{boltBucket: boltBucket{b: b}, tx: tx})
+}
@@ -0,0 +1,65 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+)
+
+// SourceBackfill is the source of the releases restored from the history of the LATEST files
+const SourceBackfill = "backfill"
+
+// Backfill adds the releases which are not stored yet inside of a single transaction and returns their keys.
+// The stored releases are never changed, and nothing is added in the dry-run mode
+func (r *Repository) Backfill(records []Record, dryRun bool, hooks ...CommitHook) ([]string, error) {
+	for i := range records {
+		if _, _, err := ParseKey(records[i].Key()); err != nil {
+			return nil, err
+		}
+	}
+
+	run := r.storage.Update
+	if dryRun {
+		run = r.storage.View
+	}
+	var added []string
+	err := run(func(tx db.Tx) error {
+		added = nil
+		var changes []Change
+		for i := range records {
+			key := records[i].Key()
+			_, err := tx.Get(key)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, db.ErrNotFound) {
+				return fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+			}
+			added = append(added, key)
+			if dryRun {
+				continue
+			}
+
+			record := records[i]
+			record.Source = SourceBackfill
+			record.Revision = 1
+			data, err := encode(&record)
+			if err != nil {
+				return err
+			}
+			if err = tx.Put(key, data); err != nil {
+				return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+			}
+			changes = append(changes, Change{Key: key, Source: SourceBackfill, After: &record})
+		}
+		if dryRun || len(changes) == 0 {
+			return nil
+		}
+		return commit(tx, changes, hooks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
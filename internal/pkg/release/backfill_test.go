@@ -0,0 +1,44 @@
+package release_test
+
+import (
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfill(t *testing.T) {
+	repo := newTestRepository(t, newTestRecord("20200102", gapps.PlatformArm, true))
+	records := []release.Record{
+		newTestRecord("20200102", gapps.PlatformArm, false),
+		newTestRecord("20200101", gapps.PlatformArm, false),
+	}
+
+	added, err := repo.Backfill(records, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/20200101"}, added)
+	_, err = repo.GetRelease("20200101", gapps.PlatformArm)
+	assert.Error(t, err)
+
+	added, err = repo.Backfill(records, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/20200101"}, added)
+
+	record, err := repo.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Equal(t, release.SourceBackfill, record.Source)
+	assert.Equal(t, uint64(1), record.Revision)
+
+	// the stored release is kept
+	record, err = repo.GetRelease("20200102", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, record.Disabled)
+	assert.NotEqual(t, release.SourceBackfill, record.Source)
+
+	added, err = repo.Backfill(records, false)
+	require.NoError(t, err)
+	assert.Empty(t, added)
+}
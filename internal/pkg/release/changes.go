@@ -0,0 +1,328 @@
+package release
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+)
+
+// Replication buckets
+const (
+	// ChangesBucket holds the change log of the releases, keyed by the sequence number
+	ChangesBucket = "changes"
+	// ReplicationBucket holds the position of the follower in the change log of its leader
+	ReplicationBucket = "replication"
+)
+
+// SourceReplication is the source of the releases removed by the full feed of the leader
+const SourceReplication = "replication"
+
+const (
+	// seqFormat keeps the change log keys sorted by the sequence number
+	seqFormat = "%020d"
+	// leaderSeqKey holds the sequence number of the last applied change of the leader
+	leaderSeqKey = "leader_seq"
+)
+
+// ChangeEvent describes the state of the release after the change, the removed release has no record
+type ChangeEvent struct {
+	Seq     uint64    `json:"seq"`
+	Key     string    `json:"key"`
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source,omitempty"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Record  *Record   `json:"record,omitempty"`
+	// Diff describes the packages changed by the merge
+	Diff *Diff `json:"diff,omitempty"`
+}
+
+// ChangeFeed holds the part of the change log returned by ChangesSince
+type ChangeFeed struct {
+	// Full feed holds all of the current releases instead of the changes, the rest of them must be removed
+	Full    bool          `json:"full,omitempty"`
+	Changes []ChangeEvent `json:"changes"`
+	// LastSeq is the sequence number to request the next changes since
+	LastSeq uint64 `json:"last_seq"`
+}
+
+// ChangesSince returns up to limit changes made after the sequence number, 0 means no limit.
+// The full feed is returned for the sequence number 0, or if it's unknown as the change log was started over
+func (r *Repository) ChangesSince(seq uint64, limit int) (*ChangeFeed, error) {
+	var feed *ChangeFeed
+	err := r.storage.View(func(tx db.Tx) error {
+		b, err := tx.Bucket(ChangesBucket)
+		if err != nil {
+			return err
+		}
+		lastSeq, err := lastChangeSeq(b)
+		if err != nil {
+			return err
+		}
+		if seq == 0 || seq > lastSeq {
+			feed, err = fullFeed(tx, lastSeq)
+			return err
+		}
+
+		feed = &ChangeFeed{Changes: []ChangeEvent{}, LastSeq: seq}
+		return b.Scan(seqKey(seq+1), "", false, func(k string, value []byte) (bool, error) {
+			e, err := decodeChangeEvent(value)
+			if err != nil {
+				return false, fmt.Errorf("unable to parse change '%s': %w", k, err)
+			}
+			feed.Changes = append(feed.Changes, *e)
+			feed.LastSeq = e.Seq
+			return limit <= 0 || len(feed.Changes) < limit, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// ApplyFeed saves the changes of the leader together with the position of the follower inside of a single transaction.
+// The changes already applied are skipped, so the same feed can be applied twice
+func (r *Repository) ApplyFeed(feed *ChangeFeed, hooks ...CommitHook) error {
+	return r.storage.Update(func(tx db.Tx) error {
+		state, err := tx.Bucket(ReplicationBucket)
+		if err != nil {
+			return err
+		}
+		seq, err := getLeaderSeq(state)
+		if err != nil {
+			return err
+		}
+
+		var changes []Change
+		applied := make(map[string]bool)
+		for _, e := range feed.Changes {
+			if !feed.Full && e.Seq <= seq {
+				continue
+			}
+			change, err := applyChangeEvent(tx, e)
+			if err != nil {
+				return err
+			}
+			applied[e.Key] = true
+			if change != nil {
+				changes = append(changes, *change)
+			}
+		}
+
+		// the full feed replaces all of the releases
+		if feed.Full {
+			var removed []string
+			err = tx.Scan("", "", false, func(key string, value []byte) (bool, error) {
+				if _, _, err := ParseKey(key); err == nil && !applied[key] {
+					removed = append(removed, key)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range removed {
+				change, err := applyChangeEvent(tx, ChangeEvent{Key: key, Source: SourceReplication, Deleted: true})
+				if err != nil {
+					return err
+				}
+				if change != nil {
+					changes = append(changes, *change)
+				}
+			}
+		}
+
+		if err = state.Put(leaderSeqKey, []byte(strconv.FormatUint(feed.LastSeq, 10))); err != nil {
+			return fmt.Errorf("unable to save the leader position: %w", err)
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return commit(tx, changes, hooks)
+	})
+}
+
+// SeedChanges adds the current releases to the empty change log and returns their number.
+// The releases saved before the change log was added have no changes, so the full feed of the leader
+// would have no position to request the next changes since, and the followers would request it again
+func (r *Repository) SeedChanges() (int, error) {
+	var n int
+	err := r.storage.Update(func(tx db.Tx) error {
+		b, err := tx.Bucket(ChangesBucket)
+		if err != nil {
+			return err
+		}
+		seq, err := lastChangeSeq(b)
+		if err != nil || seq > 0 {
+			return err
+		}
+
+		records, err := listReleases(tx, Filter{IncludeDisabled: true})
+		if err != nil {
+			return err
+		}
+		changes := make([]Change, 0, len(records))
+		for i := range records {
+			changes = append(changes, Change{Key: records[i].Key(), Source: records[i].Source, After: &records[i]})
+		}
+		n = len(changes)
+		return appendChanges(tx, changes)
+	})
+	return n, err
+}
+
+// LeaderSeq returns the sequence number of the last change applied from the leader, 0 if there is none
+func (r *Repository) LeaderSeq() (uint64, error) {
+	var seq uint64
+	err := r.storage.View(func(tx db.Tx) error {
+		state, err := tx.Bucket(ReplicationBucket)
+		if err != nil {
+			return err
+		}
+		seq, err = getLeaderSeq(state)
+		return err
+	})
+	return seq, err
+}
+
+// appendChanges adds the changes to the change log after the last one
+func appendChanges(tx db.Tx, changes []Change) error {
+	b, err := tx.Bucket(ChangesBucket)
+	if err != nil {
+		return fmt.Errorf("unable to open changes bucket: %w", err)
+	}
+	seq, err := lastChangeSeq(b)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, c := range changes {
+		seq++
+		e := ChangeEvent{Seq: seq, Key: c.Key, Time: now, Source: c.Source, Deleted: c.After == nil, Record: c.After, Diff: c.Diff}
+		data, err := json.Marshal(&e)
+		if err != nil {
+			return fmt.Errorf("unable to encode change of key '%s': %w", c.Key, err)
+		}
+		if err = b.Put(seqKey(seq), data); err != nil {
+			return fmt.Errorf("unable to put change of key '%s': %w", c.Key, err)
+		}
+	}
+	return nil
+}
+
+// applyChangeEvent saves the state of the release from the change event and returns the change.
+// It returns nil if the stored release already has this state, so the repeated full feed changes nothing
+func applyChangeEvent(tx db.Tx, e ChangeEvent) (*Change, error) {
+	change := &Change{Key: e.Key, Source: e.Source, Diff: e.Diff}
+	value, err := tx.Get(e.Key)
+	switch {
+	case err == nil:
+		if change.Before, err = decode(e.Key, value); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, db.ErrNotFound):
+		return nil, fmt.Errorf("unable to get value for key '%s' from DB: %w", e.Key, err)
+	}
+
+	if e.Deleted || e.Record == nil {
+		if change.Before == nil {
+			return nil, nil
+		}
+		if err = tx.Delete(e.Key); err != nil {
+			return nil, fmt.Errorf("unable to delete value for key '%s' from DB: %w", e.Key, err)
+		}
+		return change, nil
+	}
+
+	if _, _, err = ParseKey(e.Key); err != nil {
+		return nil, err
+	}
+	data, err := encode(e.Record)
+	if err != nil {
+		return nil, err
+	}
+	if change.Before != nil && bytes.Equal(value, data) {
+		return nil, nil
+	}
+	if err = tx.Put(e.Key, data); err != nil {
+		return nil, fmt.Errorf("unable to put value for key '%s' to DB: %w", e.Key, err)
+	}
+	change.After, err = decode(e.Key, data)
+	return change, err
+}
+
+// fullFeed returns all of the current releases as the changes up to the sequence number
+func fullFeed(tx db.Tx, lastSeq uint64) (*ChangeFeed, error) {
+	records, err := listReleases(tx, Filter{IncludeDisabled: true})
+	if err != nil {
+		return nil, err
+	}
+	feed := &ChangeFeed{Full: true, Changes: make([]ChangeEvent, 0, len(records)), LastSeq: lastSeq}
+	for i := range records {
+		feed.Changes = append(feed.Changes, ChangeEvent{
+			Seq:    lastSeq,
+			Key:    records[i].Key(),
+			Time:   time.Unix(records[i].Timestamp, 0).UTC(),
+			Source: records[i].Source,
+			Record: &records[i],
+		})
+	}
+	return feed, nil
+}
+
+// lastChangeSeq returns the sequence number of the last change in the log, 0 if it's empty
+func lastChangeSeq(b db.Bucket) (uint64, error) {
+	var seq uint64
+	err := b.Scan("", "", true, func(k string, _ []byte) (bool, error) {
+		var err error
+		if seq, err = strconv.ParseUint(k, 10, 64); err != nil {
+			return false, fmt.Errorf("bad change key '%s': %w", k, err)
+		}
+		return false, nil
+	})
+	return seq, err
+}
+
+func getLeaderSeq(state db.Bucket) (uint64, error) {
+	data, err := state.Get(leaderSeqKey)
+	if errors.Is(err, db.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	seq, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad leader position '%s': %w", data, err)
+	}
+	return seq, nil
+}
+
+func seqKey(seq uint64) string {
+	return fmt.Sprintf(seqFormat, seq)
+}
+
+// decodeChangeEvent parses the change event, restoring the platform of its record from the key
+func decodeChangeEvent(data []byte) (*ChangeEvent, error) {
+	var e ChangeEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if e.Record != nil {
+		date, p, err := ParseKey(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		if e.Record.Date == "" {
+			e.Record.Date = date
+		}
+		e.Record.Platform = p
+	}
+	return &e, nil
+}
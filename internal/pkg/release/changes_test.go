@@ -0,0 +1,140 @@
+package release_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangesSince(t *testing.T) {
+	leader := newTestRepository(t, newTestRecord("20200101", gapps.PlatformArm, false))
+	feed, err := leader.ChangesSince(0, 0)
+	require.NoError(t, err)
+	assert.True(t, feed.Full)
+	assert.Len(t, feed.Changes, 1)
+	assert.Equal(t, uint64(1), feed.LastSeq)
+
+	second := newTestRecord("20200102", gapps.PlatformArm, false)
+	require.NoError(t, leader.SaveRelease(&second))
+	_, err = leader.ApplyRetention(release.Policy{KeepLast: 1}, false)
+	require.NoError(t, err)
+
+	feed, err = leader.ChangesSince(0, 0)
+	require.NoError(t, err)
+	assert.True(t, feed.Full)
+	assert.Equal(t, uint64(3), feed.LastSeq)
+
+	feed, err = leader.ChangesSince(2, 0)
+	require.NoError(t, err)
+	assert.False(t, feed.Full)
+	require.Len(t, feed.Changes, 1)
+	assert.Equal(t, "arm/20200101", feed.Changes[0].Key)
+	assert.True(t, feed.Changes[0].Deleted)
+	assert.Equal(t, release.SourceRetention, feed.Changes[0].Source)
+
+	feed, err = leader.ChangesSince(3, 0)
+	require.NoError(t, err)
+	assert.Empty(t, feed.Changes)
+	assert.Equal(t, uint64(3), feed.LastSeq)
+
+	// the log of the leader was started over
+	feed, err = leader.ChangesSince(5, 0)
+	require.NoError(t, err)
+	assert.True(t, feed.Full)
+}
+
+func TestApplyFeed(t *testing.T) {
+	leader := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, false),
+		newTestRecord("20200101", gapps.PlatformArm64, true),
+	)
+	follower := newTestRepository(t, newTestRecord("20191231", gapps.PlatformX86, false))
+	sync := func() {
+		seq, err := follower.LeaderSeq()
+		require.NoError(t, err)
+		feed, err := leader.ChangesSince(seq, 1)
+		require.NoError(t, err)
+		require.NoError(t, follower.ApplyFeed(feed))
+	}
+
+	sync()
+	records, err := follower.ListReleases(release.Filter{IncludeDisabled: true})
+	require.NoError(t, err)
+	require.Len(t, records, 2, "the full feed replaces the releases")
+	assert.Equal(t, "arm/20200101", records[0].Key())
+	assert.True(t, records[1].Disabled)
+
+	enable := func(record *release.Record) (bool, error) {
+		record.Disabled = false
+		return true, nil
+	}
+	_, err = leader.UpdateReleases(release.Filter{Date: "20200101", IncludeDisabled: true}, enable)
+	require.NoError(t, err)
+	// the changes are fetched one by one
+	for i := 0; i < 3; i++ {
+		sync()
+	}
+
+	records, err = follower.ListReleases(release.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint64(2), records[1].Revision)
+	seq, err := follower.LeaderSeq()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), seq)
+}
+
+func TestApplyFeedEmptyLog(t *testing.T) {
+	// the releases saved before the change log was added
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	leader, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	for _, p := range []gapps.Platform{gapps.PlatformArm, gapps.PlatformArm64} {
+		record := newTestRecord("20200101", p, false)
+		data, err := json.Marshal(&record)
+		require.NoError(t, err)
+		require.NoError(t, storage.Put(record.Key(), data))
+	}
+
+	follower := newTestRepository(t)
+	sync := func() {
+		seq, err := follower.LeaderSeq()
+		require.NoError(t, err)
+		feed, err := leader.ChangesSince(seq, 0)
+		require.NoError(t, err)
+		require.NoError(t, follower.ApplyFeed(feed))
+	}
+
+	// the repeated full feed changes nothing
+	sync()
+	sync()
+	versions, err := follower.History("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+
+	// the seeded log gives the position to the follower
+	n, err := leader.SeedChanges()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = leader.SeedChanges()
+	require.NoError(t, err)
+	assert.Zero(t, n, "the log is seeded once")
+	sync()
+	seq, err := follower.LeaderSeq()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+	feed, err := leader.ChangesSince(seq, 0)
+	require.NoError(t, err)
+	assert.False(t, feed.Full)
+	assert.Empty(t, feed.Changes)
+	versions, err = follower.History("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
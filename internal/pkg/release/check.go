@@ -0,0 +1,193 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+// QuarantineBucket holds the bad values moved out of the global bucket by Check, keyed by their original keys
+const QuarantineBucket = "quarantine"
+
+// SourceRepair is the source of the releases fixed by Check
+const SourceRepair = "repair"
+
+// CheckMode describes what Check does with the bad values
+type CheckMode string
+
+// CheckMode values
+const (
+	// CheckReport only reports the bad values
+	CheckReport CheckMode = "report"
+	// CheckQuarantine moves all of the bad values to the quarantine bucket
+	CheckQuarantine CheckMode = "quarantine"
+	// CheckRepair fixes the values which can be fixed and moves the rest to the quarantine bucket
+	CheckRepair CheckMode = "repair"
+)
+
+// Problem actions
+const (
+	actionQuarantined = "quarantined"
+	actionRepaired    = "repaired"
+)
+
+// ParseCheckMode returns the CheckMode by its name
+func ParseCheckMode(s string) (CheckMode, error) {
+	switch mode := CheckMode(s); mode {
+	case CheckReport, CheckQuarantine, CheckRepair:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown check mode '%s'", s)
+}
+
+// Problem describes the bad value found by Check
+type Problem struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+	// Action is what was done with the value, it's empty in the report mode
+	Action string `json:"action,omitempty"`
+
+	// repaired holds the fixed record if the problem can be repaired
+	repaired *Record
+}
+
+// CheckResult holds the number of the checked values and the problems found by Check
+type CheckResult struct {
+	Checked  int       `json:"checked"`
+	Problems []Problem `json:"problems"`
+}
+
+// Check validates every value of the global bucket inside of a single transaction:
+// the key format, the JSON encoding, the date of the record and the names of its APIs and variants.
+// Nothing is changed in the report mode
+func (r *Repository) Check(mode CheckMode, hooks ...CommitHook) (*CheckResult, error) {
+	if _, err := ParseCheckMode(string(mode)); err != nil {
+		return nil, err
+	}
+
+	run := r.storage.Update
+	if mode == CheckReport {
+		run = r.storage.View
+	}
+	var result *CheckResult
+	err := run(func(tx db.Tx) error {
+		result = &CheckResult{Problems: []Problem{}}
+		values := make(map[string][]byte)
+		err := tx.Scan("", "", false, func(key string, value []byte) (bool, error) {
+			result.Checked++
+			if problem := checkValue(key, value); problem != nil {
+				result.Problems = append(result.Problems, *problem)
+				values[key] = value
+			}
+			return true, nil
+		})
+		if err != nil || mode == CheckReport || len(result.Problems) == 0 {
+			return err
+		}
+
+		quarantine, err := tx.Bucket(QuarantineBucket)
+		if err != nil {
+			return err
+		}
+		var (
+			changes []Change
+			keys    []string
+		)
+		for i := range result.Problems {
+			problem := &result.Problems[i]
+			change, err := fixValue(tx, quarantine, problem, values[problem.Key], mode)
+			if err != nil {
+				return err
+			}
+			if change != nil {
+				changes = append(changes, *change)
+			}
+			keys = append(keys, problem.Key)
+		}
+		// the values which can't be decoded have no changes, while their releases are gone too
+		if err = dropValidators(tx, keys); err != nil {
+			return err
+		}
+		return commit(tx, changes, hooks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fixValue repairs or quarantines the bad value according to the mode.
+// It returns the change of the release if the value holds one
+func fixValue(tx db.Tx, quarantine db.Bucket, problem *Problem, value []byte, mode CheckMode) (*Change, error) {
+	// the release is known only if the value is decoded
+	before, err := decode(problem.Key, value)
+	if err != nil {
+		before = nil
+	}
+
+	if mode == CheckRepair && problem.repaired != nil {
+		after := problem.repaired
+		after.Revision++
+		after.Source = SourceRepair
+		data, err := encode(after)
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Put(problem.Key, data); err != nil {
+			return nil, fmt.Errorf("unable to repair value for key '%s': %w", problem.Key, err)
+		}
+		problem.Action = actionRepaired
+		return &Change{Key: problem.Key, Source: SourceRepair, Before: before, After: after}, nil
+	}
+
+	if err = quarantine.Put(problem.Key, value); err != nil {
+		return nil, fmt.Errorf("unable to quarantine value for key '%s': %w", problem.Key, err)
+	}
+	if err = tx.Delete(problem.Key); err != nil {
+		return nil, fmt.Errorf("unable to delete value for key '%s' from DB: %w", problem.Key, err)
+	}
+	problem.Action = actionQuarantined
+	if before == nil {
+		return nil, nil
+	}
+	return &Change{Key: problem.Key, Source: SourceRepair, Before: before}, nil
+}
+
+// checkValue validates the stored value, it returns nil if the value is fine
+func checkValue(key string, value []byte) *Problem {
+	date, p, err := ParseKey(key)
+	if err != nil {
+		return &Problem{Key: key, Reason: err.Error()}
+	}
+	var record Record
+	if err = json.Unmarshal(value, &record); err != nil {
+		return &Problem{Key: key, Reason: fmt.Sprintf("unable to decode JSON: %s", err)}
+	}
+	record.Platform = p
+
+	for api, apiRecord := range record.APIList {
+		if _, err = gapps.AndroidString(strings.Replace(api, ".", "", -1)); err != nil {
+			return &Problem{Key: key, Reason: fmt.Sprintf("bad API '%s'", api)}
+		}
+		for _, variant := range apiRecord.VariantList {
+			if _, err = gapps.VariantString(variant.Name); err != nil {
+				return &Problem{Key: key, Reason: fmt.Sprintf("bad variant '%s' of API '%s'", variant.Name, api)}
+			}
+		}
+	}
+
+	// the key is the source of truth for the date, so the record is fixed to match it
+	if record.Date != "" && record.Date != date {
+		reason := fmt.Sprintf("date '%s' doesn't match the key", record.Date)
+		dt, _ := time.Parse(models.DateOnlyFormat, date)
+		record.Date = date
+		record.HumanDate = fmt.Sprintf(models.HumanDateTemplate, dt.Day(), dt.Month(), dt.Year())
+		return &Problem{Key: key, Reason: reason, repaired: &record}
+	}
+	return nil
+}
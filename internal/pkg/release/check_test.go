@@ -0,0 +1,68 @@
+package release_test
+
+import (
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	values := map[string]string{
+		"arm/20200101":   `{"date":"20200101","apis":{"7.1":{"variants":[{"name":"pico"}]}}}`,
+		"arm/20200102":   `{"date":"20200103"}`,
+		"arm/20200104":   `{"date":`,
+		"arm64/20200101": `{"apis":{"1.0":{"variants":[]}}}`,
+		"x86/20200101":   `{"apis":{"7.1":{"variants":[{"name":"huge"}]}}}`,
+		"20200101-arm":   `{}`,
+	}
+	problems := []string{"20200101-arm", "arm/20200102", "arm/20200104", "arm64/20200101", "x86/20200101"}
+
+	for _, mode := range []release.CheckMode{release.CheckReport, release.CheckQuarantine, release.CheckRepair} {
+		storage := db.NewMemory()
+		for key, value := range values {
+			require.NoError(t, storage.Put(key, []byte(value)))
+		}
+		repo, err := release.NewRepository(storage)
+		require.NoError(t, err)
+
+		result, err := repo.Check(mode)
+		require.NoError(t, err, mode)
+		assert.Equal(t, len(values), result.Checked, mode)
+		var keys []string
+		for _, p := range result.Problems {
+			keys = append(keys, p.Key)
+		}
+		assert.Equal(t, problems, keys, mode)
+
+		// only the values with the mismatched date are repaired
+		remaining := []string{"arm/20200101"}
+		switch mode {
+		case release.CheckReport:
+			remaining = append(remaining, problems...)
+		case release.CheckRepair:
+			remaining = append(remaining, "arm/20200102")
+			record, err := repo.GetRelease("20200102", gapps.PlatformArm)
+			require.NoError(t, err)
+			assert.Equal(t, "20200102", record.Date)
+			assert.Equal(t, release.SourceRepair, record.Source)
+		}
+		keys, err = storage.Keys()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, remaining, keys, mode)
+
+		result, err = repo.Check(release.CheckReport)
+		require.NoError(t, err, mode)
+		assert.Equal(t, mode != release.CheckReport, len(result.Problems) == 0, mode)
+		require.NoError(t, storage.Close(true))
+	}
+
+	_, err := newTestRepository(t).Check("fix")
+	assert.Error(t, err)
+}
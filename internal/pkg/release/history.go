@@ -0,0 +1,266 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+// HistoryBucket holds the versions of the releases, keyed by the release key and the version time
+const HistoryBucket = "history"
+
+// Record sources
+const (
+	SourceWatcher   = "watcher"
+	SourceAdmin     = "admin"
+	SourceImport    = "import"
+	SourceRetention = "retention"
+	// SourceUnknown is used for the records saved before the sources were tracked
+	SourceUnknown = "unknown"
+)
+
+// versionTimeFormat keeps the version keys of the release sorted by time
+const versionTimeFormat = "%020d"
+
+// Version describes the state of the release after the write.
+// The removed release has no record
+type Version struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Record  *Record   `json:"record,omitempty"`
+}
+
+// History returns the versions of the release, sorted by time.
+// The releases saved before the history was kept have no versions until they are changed
+func (r *Repository) History(date string, p gapps.Platform) ([]Version, error) {
+	versions := []Version{}
+	err := r.storage.View(func(tx db.Tx) error {
+		b, err := tx.Bucket(HistoryBucket)
+		if err != nil {
+			return err
+		}
+		key := Key(date, p)
+		return b.Scan(key+string(keySeparator), key+string(keySeparator+1), false, func(k string, value []byte) (bool, error) {
+			v, err := decodeVersion(k, value)
+			if err != nil {
+				return false, err
+			}
+			versions = append(versions, *v)
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// ListReleasesAsOf returns the releases matching the filter in the state they had at the time, sorted by date and platform.
+// The releases without versions are returned as they are now if they were saved before the time
+func (r *Repository) ListReleasesAsOf(f Filter, t time.Time) ([]Record, error) {
+	records := []Record{}
+	err := r.storage.View(func(tx db.Tx) error {
+		b, err := tx.Bucket(HistoryBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range f.platforms() {
+			from, to := f.keyRange(p)
+
+			// the last version of every release before the time, the versions are sorted by time
+			states := make(map[string]*Version)
+			err = b.Scan(from, to, false, func(k string, value []byte) (bool, error) {
+				key, _, err := parseVersionKey(k)
+				if err != nil {
+					return false, err
+				}
+				if _, ok := states[key]; !ok {
+					states[key] = nil
+				}
+				v, err := decodeVersion(k, value)
+				if err != nil {
+					return false, err
+				}
+				if !v.Time.After(t) {
+					states[key] = v
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, v := range states {
+				if v != nil && !v.Deleted {
+					records = append(records, *v.Record)
+				}
+			}
+
+			// the releases saved before the history was kept
+			err = tx.Scan(from, to, false, func(key string, value []byte) (bool, error) {
+				if _, ok := states[key]; ok {
+					return true, nil
+				}
+				record, err := decode(key, value)
+				if err != nil {
+					return false, err
+				}
+				if record.Timestamp <= t.Unix() {
+					records = append(records, *record)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := records[:0]
+	for i := range records {
+		if f.IncludeDisabled || !records[i].Disabled {
+			result = append(result, records[i])
+		}
+	}
+	sortRecords(result)
+	return result, nil
+}
+
+// appendHistory saves the new versions of the changed releases.
+// The release without versions gets the version of its previous state first, dated by its timestamp
+func appendHistory(tx db.Tx, changes []Change) error {
+	b, err := tx.Bucket(HistoryBucket)
+	if err != nil {
+		return fmt.Errorf("unable to open history bucket: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, c := range changes {
+		if c.Before != nil {
+			found := false
+			err = b.Scan(c.Key+string(keySeparator), c.Key+string(keySeparator+1), false, func(string, []byte) (bool, error) {
+				found = true
+				return false, nil
+			})
+			if err != nil {
+				return err
+			}
+			if !found {
+				source := c.Before.Source
+				if source == "" {
+					source = SourceUnknown
+				}
+				base := Version{Time: time.Unix(c.Before.Timestamp, 0).UTC(), Source: source, Record: c.Before}
+				if err = putVersion(b, c.Key, &base); err != nil {
+					return err
+				}
+			}
+		}
+
+		v := Version{Time: now, Source: c.Source, Deleted: c.After == nil, Record: c.After}
+		if err = putVersion(b, c.Key, &v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneHistory removes all of the versions of the releases
+func pruneHistory(tx db.Tx, keys []string) error {
+	b, err := tx.Bucket(HistoryBucket)
+	if err != nil {
+		return fmt.Errorf("unable to open history bucket: %w", err)
+	}
+	for _, key := range keys {
+		var versions []string
+		err = b.Scan(key+string(keySeparator), key+string(keySeparator+1), false, func(k string, _ []byte) (bool, error) {
+			versions = append(versions, k)
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+		// the versions are removed after the scan, as the cursors don't allow changes while iterating
+		for _, k := range versions {
+			if err = b.Delete(k); err != nil {
+				return fmt.Errorf("unable to delete version '%s': %w", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// putVersion saves the version, moving its time forward if there is a version with the same time
+func putVersion(b db.Bucket, key string, v *Version) error {
+	for {
+		_, err := b.Get(versionKey(key, v.Time))
+		if errors.Is(err, db.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to get version of key '%s': %w", key, err)
+		}
+		v.Time = v.Time.Add(time.Nanosecond)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode version of key '%s': %w", key, err)
+	}
+	if err = b.Put(versionKey(key, v.Time), data); err != nil {
+		return fmt.Errorf("unable to put version of key '%s': %w", key, err)
+	}
+	return nil
+}
+
+func versionKey(key string, t time.Time) string {
+	return key + string(keySeparator) + fmt.Sprintf(versionTimeFormat, t.UnixNano())
+}
+
+// parseVersionKey returns the release key and the time of the version key
+func parseVersionKey(k string) (string, time.Time, error) {
+	i := strings.LastIndexByte(k, keySeparator)
+	if i < 0 {
+		return "", time.Time{}, fmt.Errorf("%w '%s'", ErrBadKey, k)
+	}
+	nanos, err := strconv.ParseInt(k[i+1:], 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w '%s': %s", ErrBadKey, k, err)
+	}
+	return k[:i], time.Unix(0, nanos).UTC(), nil
+}
+
+func decodeVersion(k string, data []byte) (*Version, error) {
+	key, _, err := parseVersionKey(k)
+	if err != nil {
+		return nil, err
+	}
+	date, p, err := ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var v Version
+	if err = json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("unable to parse version '%s': %w", k, err)
+	}
+	if v.Record != nil {
+		if v.Record.Date == "" {
+			v.Record.Date = date
+		}
+		v.Record.Platform = p
+	}
+	return &v, nil
+}
@@ -0,0 +1,91 @@
+package release_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	legacy := newTestRecord("20200101", gapps.PlatformArm, false)
+	legacy.Timestamp = time.Now().Add(-time.Hour).Unix()
+	repo := newTestRepository(t)
+	require.NoError(t, repo.SaveRelease(&legacy))
+
+	disable := func(record *release.Record) (bool, error) {
+		record.Disabled = true
+		record.Source = release.SourceAdmin
+		return true, nil
+	}
+	_, err := repo.UpdateReleases(release.Filter{Date: "20200101"}, disable)
+	require.NoError(t, err)
+
+	versions, err := repo.History("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.False(t, versions[0].Record.Disabled)
+	assert.Equal(t, release.SourceAdmin, versions[1].Source)
+	assert.True(t, versions[1].Record.Disabled)
+	assert.Equal(t, gapps.PlatformArm, versions[1].Record.Platform)
+
+	versions, err = repo.History("20200101", gapps.PlatformArm64)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	// the history of the removed release is pruned with it
+	kept := newTestRecord("20200102", gapps.PlatformArm, false)
+	require.NoError(t, repo.SaveRelease(&kept))
+	_, err = repo.ApplyRetention(release.Policy{KeepAfter: "20200102"}, false)
+	require.NoError(t, err)
+	versions, err = repo.History("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+	versions, err = repo.History("20200102", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestListReleasesAsOf(t *testing.T) {
+	// saved before the history was kept
+	legacy := newTestRecord("20200101", gapps.PlatformArm64, false)
+	repo := newTestRepository(t, legacy)
+
+	beforeAll := time.Unix(legacy.Timestamp-1, 0)
+	first := newTestRecord("20200101", gapps.PlatformArm, false)
+	first.Timestamp = time.Now().Unix()
+	require.NoError(t, repo.SaveRelease(&first))
+	afterSave := time.Now()
+
+	_, err := repo.UpdateReleases(release.Filter{Date: "20200101"}, func(record *release.Record) (bool, error) {
+		record.Disabled = true
+		return true, nil
+	})
+	require.NoError(t, err)
+	afterDisable := time.Now()
+
+	cases := []struct {
+		name            string
+		t               time.Time
+		want            []string
+		includeDisabled bool
+	}{
+		{"before all", beforeAll, []string{}, false},
+		{"after save", afterSave, []string{"arm/20200101", "arm64/20200101"}, false},
+		{"after disable", afterDisable, []string{}, false},
+		{"after disable with disabled", afterDisable, []string{"arm/20200101", "arm64/20200101"}, true},
+	}
+	for _, c := range cases {
+		records, err := repo.ListReleasesAsOf(release.Filter{IncludeDisabled: c.includeDisabled}, c.t)
+		require.NoError(t, err, c.name)
+		keys := make([]string, 0, len(records))
+		for i := range records {
+			keys = append(keys, records[i].Key())
+		}
+		assert.Equal(t, c.want, keys, c.name)
+	}
+}
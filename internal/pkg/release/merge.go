@@ -0,0 +1,141 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+)
+
+// Diff lists the packages of the release changed by the merge as "<api>/<variant>"
+type Diff struct {
+	Added []string `json:"added,omitempty"`
+	// Removed packages are missing in the merged release, they are kept in the record unless they are removed by the merge
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Empty reports if nothing was added or removed
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// MergeResult describes the release saved by MergeRelease
+type MergeResult struct {
+	// Created reports if the release is new
+	Created bool
+	// Diff is nil if the release is new or nothing was changed
+	Diff *Diff
+}
+
+// MergeRelease saves the new release, or merges its packages into the stored one inside of a single transaction.
+// The packages missing in the new release are reported, and they are removed from the stored one with removeMissing.
+// The rest of the stored release is kept, e.g. it stays disabled
+func (r *Repository) MergeRelease(record *Record, removeMissing bool, hooks ...CommitHook) (*MergeResult, error) {
+	key := record.Key()
+	var result *MergeResult
+	err := r.storage.Update(func(tx db.Tx) error {
+		result = &MergeResult{}
+		change := Change{Key: key, Source: record.Source}
+		value, err := tx.Get(key)
+		switch {
+		case errors.Is(err, db.ErrNotFound), errors.Is(err, db.ErrNilValue):
+			result.Created = true
+			merged := *record
+			merged.Revision = 1
+			change.After = &merged
+		case err != nil:
+			return fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+		default:
+			if change.Before, err = decode(key, value); err != nil {
+				return err
+			}
+			merged := *change.Before
+			diff := merged.merge(record.ArchRecord, removeMissing)
+			if diff.Empty() {
+				return nil
+			}
+			result.Diff = &diff
+			if len(diff.Added) == 0 && !removeMissing {
+				// only the missing packages are reported, the record isn't changed
+				return nil
+			}
+			merged.Source = record.Source
+			merged.Timestamp = record.Timestamp
+			merged.Revision++
+			change.After = &merged
+			change.Diff = &diff
+		}
+
+		data, err := encode(change.After)
+		if err != nil {
+			return err
+		}
+		if err = tx.Put(key, data); err != nil {
+			return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+		}
+		return commit(tx, []Change{change}, hooks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// merge adds the packages of the fetched release missing in the record and returns the diff.
+// The variants follow the order of the fetched release, the ones missing in it go last unless they are removed
+func (r *Record) merge(fetched models.ArchRecord, removeMissing bool) Diff {
+	var diff Diff
+	apis := make(map[string]models.APIRecord)
+	for _, api := range sortedAPIs(fetched.APIList) {
+		stored := r.APIList[api].VariantList
+		var variants []models.APIVariant
+		for _, variant := range fetched.APIList[api].VariantList {
+			if i := variantIndex(stored, variant.Name); i >= 0 {
+				variants = append(variants, stored[i])
+				continue
+			}
+			variants = append(variants, variant)
+			diff.Added = append(diff.Added, api+"/"+variant.Name)
+		}
+		apis[api] = models.APIRecord{VariantList: variants}
+	}
+
+	for _, api := range sortedAPIs(r.APIList) {
+		variants := apis[api].VariantList
+		for _, variant := range r.APIList[api].VariantList {
+			if variantIndex(fetched.APIList[api].VariantList, variant.Name) >= 0 {
+				continue
+			}
+			diff.Removed = append(diff.Removed, api+"/"+variant.Name)
+			if !removeMissing {
+				variants = append(variants, variant)
+			}
+		}
+		if len(variants) > 0 {
+			apis[api] = models.APIRecord{VariantList: variants}
+		}
+	}
+	r.APIList = apis
+	return diff
+}
+
+func sortedAPIs(apis map[string]models.APIRecord) []string {
+	names := make([]string, 0, len(apis))
+	for api := range apis {
+		names = append(names, api)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// variantIndex returns the index of the variant with the name, -1 if there is none
+func variantIndex(variants []models.APIVariant, name string) int {
+	for i, variant := range variants {
+		if variant.Name == name {
+			return i
+		}
+	}
+	return -1
+}
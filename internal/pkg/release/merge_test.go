@@ -0,0 +1,90 @@
+package release_test
+
+import (
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIs(apis map[string][]string) map[string]models.APIRecord {
+	result := make(map[string]models.APIRecord)
+	for api, variants := range apis {
+		var list []models.APIVariant
+		for _, variant := range variants {
+			list = append(list, models.APIVariant{Name: variant})
+		}
+		result[api] = models.APIRecord{VariantList: list}
+	}
+	return result
+}
+
+func variantNames(record *release.Record, api string) []string {
+	var names []string
+	for _, variant := range record.APIList[api].VariantList {
+		names = append(names, variant.Name)
+	}
+	return names
+}
+
+func TestMergeRelease(t *testing.T) {
+	stored := newTestRecord("20200101", gapps.PlatformArm, true)
+	stored.APIList = newTestAPIs(map[string][]string{"9.0": {"pico", "nano"}, "10.0": {"pico"}})
+	repo := newTestRepository(t, stored)
+
+	fetched := newTestRecord("20200101", gapps.PlatformArm, false)
+	fetched.Source = release.SourceWatcher
+	fetched.APIList = newTestAPIs(map[string][]string{"9.0": {"pico", "micro", "nano"}, "11.0": {"pico"}})
+	result, err := repo.MergeRelease(&fetched, false)
+	require.NoError(t, err)
+	assert.False(t, result.Created)
+	require.NotNil(t, result.Diff)
+	assert.Equal(t, []string{"11.0/pico", "9.0/micro"}, result.Diff.Added)
+	assert.Equal(t, []string{"10.0/pico"}, result.Diff.Removed)
+
+	record, err := repo.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, record.Disabled)
+	assert.Equal(t, release.SourceWatcher, record.Source)
+	assert.Equal(t, uint64(2), record.Revision)
+	assert.Equal(t, []string{"pico", "micro", "nano"}, variantNames(record, "9.0"))
+	assert.Equal(t, []string{"pico"}, variantNames(record, "10.0"))
+	assert.Equal(t, []string{"pico"}, variantNames(record, "11.0"))
+
+	feed, err := repo.ChangesSince(1, 0)
+	require.NoError(t, err)
+	require.Len(t, feed.Changes, 1)
+	assert.Equal(t, result.Diff, feed.Changes[0].Diff)
+
+	// the removed packages are only reported again
+	result, err = repo.MergeRelease(&fetched, false)
+	require.NoError(t, err)
+	require.NotNil(t, result.Diff)
+	assert.Empty(t, result.Diff.Added)
+	record, err = repo.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), record.Revision)
+
+	result, err = repo.MergeRelease(&fetched, true)
+	require.NoError(t, err)
+	require.NotNil(t, result.Diff)
+	assert.Equal(t, []string{"10.0/pico"}, result.Diff.Removed)
+	record, err = repo.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.NotContains(t, record.APIList, "10.0")
+	assert.True(t, record.Disabled)
+
+	result, err = repo.MergeRelease(&fetched, true)
+	require.NoError(t, err)
+	assert.Nil(t, result.Diff)
+
+	created := newTestRecord("20200102", gapps.PlatformArm, false)
+	result, err = repo.MergeRelease(&created, false)
+	require.NoError(t, err)
+	assert.True(t, result.Created)
+	assert.Nil(t, result.Diff)
+}
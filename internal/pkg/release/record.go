@@ -0,0 +1,79 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+const (
+	// keyTemplate describes the format of the release keys inside of the storage.
+	// The platform goes first, so the releases of a platform are stored together in the date order
+	keyTemplate  = "%s" + string(keySeparator) + "%s"
+	keySeparator = '/'
+	// keyMax is bigger than any of the key characters, it's used for the inclusive upper bounds
+	keyMax = "~"
+)
+
+// ErrBadKey is returned for the storage keys which don't follow the release key format
+var ErrBadKey = errors.New("bad release key")
+
+// Record is used to store models.ArchRecord in DB
+type Record struct {
+	models.ArchRecord
+
+	// Platform is encoded in the key, so it's not stored in the value
+	Platform gapps.Platform `json:"-"`
+	Disabled bool           `json:"disabled,omitempty"`
+	// Source describes the origin of the latest change
+	Source string `json:"source,omitempty"`
+	// Pinned releases are never removed by the retention
+	Pinned    bool  `json:"pinned,omitempty"`
+	Timestamp int64 `json:"ts"`
+	// Revision is increased on every change of the record
+	Revision uint64 `json:"rev,omitempty"`
+}
+
+// Key returns the storage key of the record
+func (r *Record) Key() string {
+	return Key(r.Date, r.Platform)
+}
+
+// Key returns the storage key for the release date and platform
+func Key(date string, p gapps.Platform) string {
+	return fmt.Sprintf(keyTemplate, p, date)
+}
+
+// ParseKey returns the release date and platform encoded in the storage key
+func ParseKey(key string) (string, gapps.Platform, error) {
+	arch, date, ok := strings.Cut(key, string(keySeparator))
+	if !ok {
+		return "", 0, fmt.Errorf("%w '%s'", ErrBadKey, key)
+	}
+	p, err := gapps.PlatformString(arch)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w '%s': %s", ErrBadKey, key, err)
+	}
+	if _, err = time.Parse(models.DateOnlyFormat, date); err != nil {
+		return "", 0, fmt.Errorf("%w '%s': %s", ErrBadKey, key, err)
+	}
+	return date, p, nil
+}
+
+// keyRange returns the [from, to) key range of the platform releases between the dates, both inclusive.
+// The empty date leaves the range open from its side
+func keyRange(p gapps.Platform, fromDate, toDate string) (string, string) {
+	from := p.String() + string(keySeparator)
+	if fromDate != "" {
+		from = Key(fromDate, p)
+	}
+	to := p.String() + string(keySeparator+1)
+	if toDate != "" {
+		to = Key(toDate, p) + keyMax
+	}
+	return from, to
+}
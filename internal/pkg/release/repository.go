@@ -0,0 +1,327 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Filter describes the subset of releases returned by ListReleases
+type Filter struct {
+	// Platforms limits the result to the provided platforms, all of them are returned if it's empty
+	Platforms []gapps.Platform
+	// Date limits the result to the releases of the date if it's not empty, it overrides From and To
+	Date string
+	// From and To limit the result to the releases between the dates, both inclusive.
+	// The range is open from the side of the empty date
+	From, To string
+	// IncludeDisabled adds the disabled releases to the result
+	IncludeDisabled bool
+}
+
+// keyRange returns the key range of the platform releases matching the filter dates
+func (f *Filter) keyRange(p gapps.Platform) (string, string) {
+	if f.Date != "" {
+		return keyRange(p, f.Date, f.Date)
+	}
+	return keyRange(p, f.From, f.To)
+}
+
+func (f *Filter) platforms() []gapps.Platform {
+	if len(f.Platforms) == 0 {
+		return gapps.PlatformValues()
+	}
+	return f.Platforms
+}
+
+// UpdateResult lists the keys of the releases processed by UpdateReleases
+type UpdateResult struct {
+	Matched []string
+	Changed []string
+	// Records hold the matched releases after the update
+	Records []Record
+}
+
+// Change describes the record saved by the repository.
+// Before is nil for the added record, and After is nil for the removed one
+type Change struct {
+	Key    string
+	Source string
+	Before *Record
+	After  *Record
+	// Diff describes the packages changed by the merge
+	Diff *Diff
+}
+
+// CommitHook is called at the end of the transaction which saved the changes.
+// Nothing is saved if it fails
+type CommitHook func(tx db.Tx, changes []Change) error
+
+// commit saves the history of the changes and calls the hooks in order, stopping on the first error.
+// The validators of the releases removed or replaced by anything but the watcher are dropped,
+// so the release sources are asked for them again
+func commit(tx db.Tx, changes []Change, hooks []CommitHook) error {
+	if err := appendHistory(tx, changes); err != nil {
+		return err
+	}
+	if err := appendChanges(tx, changes); err != nil {
+		return err
+	}
+	var replaced []string
+	for _, c := range changes {
+		if c.Source != SourceWatcher {
+			replaced = append(replaced, c.Key)
+		}
+	}
+	if err := dropValidators(tx, replaced); err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		if err := hook(tx, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Repository provides typed access to the release records in the storage.
+// It's the only place which knows about the key format and the record encoding
+type Repository struct {
+	storage db.Storage
+}
+
+// NewRepository creates new instance of Repository
+func NewRepository(storage db.Storage) (*Repository, error) {
+	if storage == nil {
+		return nil, errors.New("storage is nil")
+	}
+	return &Repository{storage: storage}, nil
+}
+
+// GetRelease returns the release record for the date and platform.
+// The error wraps db.ErrNotFound if there is no such release
+func (r *Repository) GetRelease(date string, p gapps.Platform) (*Record, error) {
+	key := Key(date, p)
+	data, err := r.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return decode(key, data)
+}
+
+// LatestEnabled returns the latest release for the platform which wasn't disabled.
+// It returns nil if there is no such release
+func (r *Repository) LatestEnabled(p gapps.Platform) (*Record, error) {
+	from, to := keyRange(p, "", "")
+	return r.lastRelease(from, to, func(record *Record) bool { return !record.Disabled })
+}
+
+// PreviousRelease returns the latest release for the platform before the date, disabled ones included.
+// It returns nil if there is no such release
+func (r *Repository) PreviousRelease(date string, p gapps.Platform) (*Record, error) {
+	from, _ := keyRange(p, "", "")
+	return r.lastRelease(from, Key(date, p), func(*Record) bool { return true })
+}
+
+// ListReleases returns the releases matching the filter, sorted by date and platform
+func (r *Repository) ListReleases(f Filter) ([]Record, error) {
+	var records []Record
+	err := r.storage.View(func(tx db.Tx) error {
+		var err error
+		records, err = listReleases(tx, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// SaveRelease stores the release record if the stored one has the same revision, 0 means there is no such record.
+// The revision of the record is increased on success, the error wraps ErrConflict on the revision mismatch
+func (r *Repository) SaveRelease(record *Record, hooks ...CommitHook) error {
+	key := record.Key()
+	return r.storage.Update(func(tx db.Tx) error {
+		change := Change{Key: key, Source: record.Source, After: record}
+		var revision uint64
+		value, err := tx.Get(key)
+		switch {
+		case err == nil:
+			if change.Before, err = decode(key, value); err != nil {
+				return err
+			}
+			revision = change.Before.Revision
+		case !errors.Is(err, db.ErrNotFound):
+			return fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+		}
+		if record.Revision != revision {
+			current := []Record{}
+			if change.Before != nil {
+				current = append(current, *change.Before)
+			}
+			return &ConflictError{Current: current}
+		}
+
+		record.Revision++
+		data, err := encode(record)
+		if err != nil {
+			record.Revision--
+			return err
+		}
+		if err = tx.Put(key, data); err != nil {
+			return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+		}
+		return commit(tx, []Change{change}, hooks)
+	})
+}
+
+// UpdateReleases applies the function to every release matching the filter inside of a single transaction.
+// The record is saved only if the function reports it as changed, its revision is increased then.
+// Nothing is saved if the function fails for any of the records
+func (r *Repository) UpdateReleases(f Filter, fn func(record *Record) (bool, error), hooks ...CommitHook) (*UpdateResult, error) {
+	return r.UpdateReleasesIf(f, nil, fn, hooks...)
+}
+
+// UpdateReleasesIf works as UpdateReleases if the ETag of the matching releases is one of the passed ones,
+// or "*" is passed, or nothing is. Otherwise the error wraps ErrConflict and holds the current releases
+func (r *Repository) UpdateReleasesIf(f Filter, ifMatch []string, fn func(record *Record) (bool, error), hooks ...CommitHook) (*UpdateResult, error) {
+	var result *UpdateResult
+	err := r.storage.Update(func(tx db.Tx) error {
+		result = &UpdateResult{}
+		records, err := listReleases(tx, f)
+		if err != nil {
+			return err
+		}
+		if !matchETag(ETag(records), ifMatch) {
+			return &ConflictError{Current: records}
+		}
+
+		var changes []Change
+		for i := range records {
+			key := records[i].Key()
+			result.Matched = append(result.Matched, key)
+			before := records[i]
+			changed, err := fn(&records[i])
+			if err != nil {
+				return fmt.Errorf("unable to update record for key '%s': %w", key, err)
+			}
+			if !changed {
+				continue
+			}
+
+			records[i].Revision = before.Revision + 1
+			data, err := encode(&records[i])
+			if err != nil {
+				return err
+			}
+			if err = tx.Put(key, data); err != nil {
+				return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+			}
+			result.Changed = append(result.Changed, key)
+			changes = append(changes, Change{Key: key, Source: records[i].Source, Before: &before, After: &records[i]})
+		}
+		result.Records = records
+		return commit(tx, changes, hooks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// lastRelease returns the latest release in the [from, to) key range accepted by the function
+func (r *Repository) lastRelease(from, to string, accept func(record *Record) bool) (*Record, error) {
+	var result *Record
+	err := r.storage.Scan(from, to, true, func(key string, value []byte) (bool, error) {
+		record := decodeOrSkip(key, value)
+		if record == nil || !accept(record) {
+			return true, nil
+		}
+		result = record
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// listReleases returns the releases matching the filter from the bucket, sorted by date and platform
+func listReleases(b db.Bucket, f Filter) ([]Record, error) {
+	records := []Record{}
+	for _, p := range f.platforms() {
+		from, to := f.keyRange(p)
+		err := b.Scan(from, to, false, func(key string, value []byte) (bool, error) {
+			record := decodeOrSkip(key, value)
+			if record == nil {
+				return true, nil
+			}
+			if f.IncludeDisabled || !record.Disabled {
+				records = append(records, *record)
+			}
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortRecords(records)
+	return records, nil
+}
+
+// sortRecords sorts the records by date and platform.
+// The keys are sorted by platform first, while the clients expect the release order
+func sortRecords(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Date != records[j].Date {
+			return records[i].Date < records[j].Date
+		}
+		return records[i].Platform.String() < records[j].Platform.String()
+	})
+}
+
+func encode(record *Record) ([]byte, error) {
+	if record == nil {
+		return nil, errors.New("record is nil")
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode record for key '%s': %w", record.Key(), err)
+	}
+	return data, nil
+}
+
+// decodeOrSkip returns the decoded record, or nil if the value is bad.
+// The bad values are left to Check, so they are logged and skipped by the reads instead of breaking them
+func decodeOrSkip(key string, data []byte) *Record {
+	record, err := decode(key, data)
+	if err != nil {
+		log.WithError(err).WithField("key", key).Warn("Skipping the bad value, run the check to quarantine it")
+		return nil
+	}
+	return record
+}
+
+func decode(key string, data []byte) (*Record, error) {
+	date, p, err := ParseKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse record for key '%s': %w", key, err)
+	}
+
+	var record Record
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("unable to parse record for key '%s': %w", key, err)
+	}
+	if record.Date == "" {
+		record.Date = date
+	}
+	record.Platform = p
+	return &record, nil
+}
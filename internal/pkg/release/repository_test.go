@@ -0,0 +1,273 @@
+package release_test
+
+import (
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T, records ...release.Record) *release.Repository {
+	log.SetLevel(log.FatalLevel) // ignore DB logging for tests
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+
+	repo, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, repo.SaveRelease(&record))
+	}
+	return repo
+}
+
+func newTestRecord(date string, p gapps.Platform, disabled bool) release.Record {
+	return release.Record{
+		ArchRecord: models.ArchRecord{Date: date},
+		Platform:   p,
+		Disabled:   disabled,
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	date, p, err := release.ParseKey(release.Key("20200101", gapps.PlatformX86_64))
+	require.NoError(t, err)
+	assert.Equal(t, "20200101", date)
+	assert.Equal(t, gapps.PlatformX86_64, p)
+
+	for _, key := range []string{"", "20200101", "arm/", "arm/2020", "mips/20200101", "20200101-arm", "20200101/arm"} {
+		_, _, err = release.ParseKey(key)
+		assert.ErrorIs(t, err, release.ErrBadKey, key)
+	}
+}
+
+func TestGetRelease(t *testing.T) {
+	repo := newTestRepository(t, newTestRecord("20200101", gapps.PlatformArm, true))
+
+	record, err := repo.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Equal(t, "20200101", record.Date)
+	assert.Equal(t, gapps.PlatformArm, record.Platform)
+	assert.True(t, record.Disabled)
+
+	_, err = repo.GetRelease("20200101", gapps.PlatformArm64)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func TestLatestEnabled(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, false),
+		newTestRecord("20200102", gapps.PlatformArm, false),
+		newTestRecord("20200103", gapps.PlatformArm, true),
+		newTestRecord("20200104", gapps.PlatformArm64, false),
+		newTestRecord("20200101", gapps.PlatformX86, true),
+	)
+
+	record, err := repo.LatestEnabled(gapps.PlatformArm)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, "20200102", record.Date)
+
+	record, err = repo.LatestEnabled(gapps.PlatformArm64)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, "20200104", record.Date)
+
+	for _, p := range []gapps.Platform{gapps.PlatformX86, gapps.PlatformX86_64} {
+		record, err = repo.LatestEnabled(p)
+		require.NoError(t, err)
+		assert.Nil(t, record, p.String())
+	}
+}
+
+func TestPreviousRelease(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, false),
+		newTestRecord("20200103", gapps.PlatformArm, true),
+		newTestRecord("20200102", gapps.PlatformArm64, false),
+	)
+
+	record, err := repo.PreviousRelease("20200105", gapps.PlatformArm)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, "20200103", record.Date)
+
+	record, err = repo.PreviousRelease("20200103", gapps.PlatformArm)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, "20200101", record.Date)
+
+	record, err = repo.PreviousRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Nil(t, record)
+}
+
+func TestListReleases(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200102", gapps.PlatformArm, false),
+		newTestRecord("20200101", gapps.PlatformArm64, false),
+		newTestRecord("20200101", gapps.PlatformArm, true),
+		newTestRecord("20200102", gapps.PlatformX86_64, false),
+	)
+
+	cases := []struct {
+		name   string
+		filter release.Filter
+		want   []string
+	}{
+		{"all enabled", release.Filter{}, []string{"arm64/20200101", "arm/20200102", "x86_64/20200102"}},
+		{"all", release.Filter{IncludeDisabled: true}, []string{"arm/20200101", "arm64/20200101", "arm/20200102", "x86_64/20200102"}},
+		{"platform", release.Filter{Platforms: []gapps.Platform{gapps.PlatformArm}, IncludeDisabled: true}, []string{"arm/20200101", "arm/20200102"}},
+		{"platforms", release.Filter{Platforms: []gapps.Platform{gapps.PlatformArm64, gapps.PlatformX86_64}}, []string{"arm64/20200101", "x86_64/20200102"}},
+		{"date", release.Filter{Date: "20200101", IncludeDisabled: true}, []string{"arm/20200101", "arm64/20200101"}},
+		{"from", release.Filter{From: "20200102"}, []string{"arm/20200102", "x86_64/20200102"}},
+		{"to", release.Filter{To: "20200101", IncludeDisabled: true}, []string{"arm/20200101", "arm64/20200101"}},
+		{"range", release.Filter{From: "20200101", To: "20200102", Platforms: []gapps.Platform{gapps.PlatformArm}}, []string{"arm/20200102"}},
+		{"none", release.Filter{Platforms: []gapps.Platform{gapps.PlatformX86}}, []string{}},
+	}
+	for _, c := range cases {
+		records, err := repo.ListReleases(c.filter)
+		require.NoError(t, err, c.name)
+		keys := make([]string, 0, len(records))
+		for i := range records {
+			keys = append(keys, records[i].Key())
+		}
+		assert.Equal(t, c.want, keys, c.name)
+	}
+}
+
+func TestUpdateReleases(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, true),
+		newTestRecord("20200101", gapps.PlatformArm64, false),
+		newTestRecord("20200101", gapps.PlatformX86, false),
+		newTestRecord("20200102", gapps.PlatformArm, false),
+	)
+	disable := func(record *release.Record) (bool, error) {
+		if record.Disabled {
+			return false, nil
+		}
+		record.Disabled = true
+		return true, nil
+	}
+
+	result, err := repo.UpdateReleases(release.Filter{Date: "20200101", IncludeDisabled: true}, disable)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/20200101", "arm64/20200101", "x86/20200101"}, result.Matched)
+	assert.Equal(t, []string{"arm64/20200101", "x86/20200101"}, result.Changed)
+
+	records, err := repo.ListReleases(release.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "arm/20200102", records[0].Key())
+}
+
+func TestUpdateReleasesRollback(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, false),
+		newTestRecord("20200101", gapps.PlatformArm64, false),
+	)
+
+	_, err := repo.UpdateReleases(release.Filter{Date: "20200101"}, func(record *release.Record) (bool, error) {
+		if record.Platform == gapps.PlatformArm64 {
+			return false, assert.AnError
+		}
+		record.Disabled = true
+		return true, nil
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	// the first record was not saved
+	records, err := repo.ListReleases(release.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestSaveReleaseConflict(t *testing.T) {
+	repo := newTestRepository(t)
+	record := newTestRecord("20200101", gapps.PlatformArm, false)
+	require.NoError(t, repo.SaveRelease(&record))
+	assert.Equal(t, uint64(1), record.Revision)
+
+	stale := newTestRecord("20200101", gapps.PlatformArm, true)
+	err := repo.SaveRelease(&stale)
+	require.ErrorIs(t, err, release.ErrConflict)
+	var conflict *release.ConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Len(t, conflict.Current, 1)
+	assert.False(t, conflict.Current[0].Disabled)
+	assert.Equal(t, uint64(0), stale.Revision)
+
+	record.Disabled = true
+	require.NoError(t, repo.SaveRelease(&record))
+	stored, err := repo.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, stored.Disabled)
+	assert.Equal(t, uint64(2), stored.Revision)
+}
+
+func TestUpdateReleasesIf(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, false),
+		newTestRecord("20200101", gapps.PlatformArm64, false),
+	)
+	f := release.Filter{Date: "20200101", IncludeDisabled: true}
+	records, err := repo.ListReleases(f)
+	require.NoError(t, err)
+	etag := release.ETag(records)
+	disable := func(record *release.Record) (bool, error) {
+		record.Disabled = true
+		return true, nil
+	}
+
+	result, err := repo.UpdateReleasesIf(f, []string{`"stale"`, etag}, disable)
+	require.NoError(t, err)
+	require.Len(t, result.Records, 2)
+	assert.Equal(t, uint64(2), result.Records[0].Revision)
+	assert.NotEqual(t, etag, release.ETag(result.Records))
+
+	// the releases were changed since the ETag was computed
+	_, err = repo.UpdateReleasesIf(f, []string{etag}, disable)
+	var conflict *release.ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, release.ETag(result.Records), release.ETag(conflict.Current))
+
+	_, err = repo.UpdateReleasesIf(f, []string{"*"}, disable)
+	assert.NoError(t, err)
+}
+
+func TestBadValue(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	repo, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	for _, date := range []string{"20200101", "20200102"} {
+		record := newTestRecord(date, gapps.PlatformArm, false)
+		require.NoError(t, repo.SaveRelease(&record))
+	}
+	require.NoError(t, storage.Put(release.Key("20200103", gapps.PlatformArm), []byte("{")))
+
+	// the bad value is skipped by the reads and left to the check
+	records, err := repo.ListReleases(release.Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	record, err := repo.LatestEnabled(gapps.PlatformArm)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, "20200102", record.Date)
+	feed, err := repo.ChangesSince(0, 0)
+	require.NoError(t, err)
+	assert.Len(t, feed.Changes, 2)
+
+	removed, err := repo.ApplyRetention(release.Policy{KeepLast: 1}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arm/20200101"}, removed)
+	_, err = storage.Get(release.Key("20200103", gapps.PlatformArm))
+	assert.NoError(t, err, "the bad value is not removed by the retention")
+}
@@ -0,0 +1,137 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Policy describes the releases kept by ApplyRetention, the release is kept if any of the rules keeps it.
+// The pinned releases and the latest enabled release of every platform are always kept
+type Policy struct {
+	// KeepLast keeps the last N releases of every platform, 0 disables the rule
+	KeepLast int
+	// KeepAfter keeps the releases of the date and newer ones, the empty date disables the rule
+	KeepAfter string
+}
+
+// Enabled reports if the policy has any of the rules, nothing is removed otherwise
+func (p *Policy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepAfter != ""
+}
+
+// Validate checks if the policy rules are valid
+func (p *Policy) Validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("bad number of the kept releases %d", p.KeepLast)
+	}
+	if p.KeepAfter != "" {
+		if _, err := time.Parse(models.DateOnlyFormat, p.KeepAfter); err != nil {
+			return fmt.Errorf("bad date '%s'", p.KeepAfter)
+		}
+	}
+	return nil
+}
+
+// keeps reports if the release is kept by the rules, n is the number of the newer releases of the platform
+func (p *Policy) keeps(record *Record, n int) bool {
+	return record.Pinned ||
+		(p.KeepLast > 0 && n < p.KeepLast) ||
+		(p.KeepAfter != "" && record.Date >= p.KeepAfter)
+}
+
+// ApplyRetention removes the releases which are not kept by the policy inside of a single transaction
+// and returns their keys. In the dry-run mode nothing is removed
+func (r *Repository) ApplyRetention(p Policy, dryRun bool, hooks ...CommitHook) ([]string, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if !p.Enabled() {
+		return nil, nil
+	}
+
+	run := r.storage.Update
+	if dryRun {
+		run = r.storage.View
+	}
+	var removed []string
+	err := run(func(tx db.Tx) error {
+		removed = nil
+		var changes []Change
+		for _, platform := range gapps.PlatformValues() {
+			from, to := keyRange(platform, "", "")
+			n, enabledFound := 0, false
+			err := tx.Scan(from, to, true, func(key string, value []byte) (bool, error) {
+				// the bad values are neither counted nor removed, they are left to the check
+				record := decodeOrSkip(key, value)
+				if record == nil {
+					return true, nil
+				}
+				latestEnabled := !record.Disabled && !enabledFound
+				enabledFound = enabledFound || !record.Disabled
+				if !latestEnabled && !p.keeps(record, n) {
+					removed = append(removed, key)
+					changes = append(changes, Change{Key: key, Source: SourceRetention, Before: record})
+				}
+				n++
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		// nothing is committed without changes, so the hooks don't see empty commits
+		if dryRun || len(changes) == 0 {
+			return nil
+		}
+
+		// the keys are removed after the scan, as the cursors don't allow changes while iterating
+		for _, key := range removed {
+			if err := tx.Delete(key); err != nil {
+				return fmt.Errorf("unable to delete value for key '%s' from DB: %w", key, err)
+			}
+		}
+		if err := commit(tx, changes, hooks); err != nil {
+			return err
+		}
+		// the history of the removed releases is not served, so it doesn't grow forever
+		return pruneHistory(tx, removed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// WatchRetention applies the policy right away and then with the interval until the context is canceled
+func (r *Repository) WatchRetention(ctx context.Context, p Policy, interval time.Duration, hooks ...CommitHook) {
+	apply := func() {
+		removed, err := r.ApplyRetention(p, false, hooks...)
+		if err != nil {
+			log.WithError(err).Error("Unable to apply the retention policy")
+			return
+		}
+		if len(removed) > 0 {
+			log.WithField("keys", removed).Info("Removed the old releases")
+		}
+	}
+
+	apply()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Context canceled, exiting retention watcher")
+			return
+		case <-ticker.C:
+			apply()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package release_test
+
+import (
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyRetention(t *testing.T) {
+	pinned := newTestRecord("20200101", gapps.PlatformArm, false)
+	pinned.Pinned = true
+	records := []release.Record{
+		pinned,
+		newTestRecord("20200102", gapps.PlatformArm, false),
+		newTestRecord("20200103", gapps.PlatformArm, false),
+		newTestRecord("20200104", gapps.PlatformArm, true),
+		newTestRecord("20200105", gapps.PlatformArm, false),
+		newTestRecord("20200101", gapps.PlatformArm64, false),
+		newTestRecord("20200102", gapps.PlatformArm64, true),
+		newTestRecord("20200103", gapps.PlatformArm64, true),
+	}
+
+	cases := []struct {
+		name   string
+		policy release.Policy
+		want   []string
+	}{
+		{"disabled", release.Policy{}, nil},
+		{"keep last", release.Policy{KeepLast: 2}, []string{"arm/20200103", "arm/20200102"}},
+		// the latest enabled arm64 release is kept
+		{"keep last one", release.Policy{KeepLast: 1}, []string{"arm/20200104", "arm/20200103", "arm/20200102", "arm64/20200102"}},
+		{"keep after", release.Policy{KeepAfter: "20200103"}, []string{"arm/20200102", "arm64/20200102"}},
+		{"any rule", release.Policy{KeepLast: 4, KeepAfter: "20200105"}, nil},
+	}
+	for _, c := range cases {
+		repo := newTestRepository(t, records...)
+
+		removed, err := repo.ApplyRetention(c.policy, true)
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.want, removed, c.name)
+		all, err := repo.ListReleases(release.Filter{IncludeDisabled: true})
+		require.NoError(t, err, c.name)
+		assert.Len(t, all, len(records), "dry run must not remove anything")
+
+		commits := 0
+		hook := func(db.Tx, []release.Change) error {
+			commits++
+			return nil
+		}
+		removed, err = repo.ApplyRetention(c.policy, false, hook)
+		require.NoError(t, err, c.name)
+		assert.Equal(t, c.want, removed, c.name)
+		assert.Equal(t, len(c.want) > 0, commits == 1, "only the changes must be committed")
+		all, err = repo.ListReleases(release.Filter{IncludeDisabled: true})
+		require.NoError(t, err, c.name)
+		assert.Len(t, all, len(records)-len(c.want), c.name)
+	}
+
+	_, err := newTestRepository(t).ApplyRetention(release.Policy{KeepAfter: "2020-01-01"}, false)
+	assert.Error(t, err)
+}
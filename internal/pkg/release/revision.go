@@ -0,0 +1,53 @@
+package release
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// anyETag matches any of the ETags
+const anyETag = "*"
+
+// ErrConflict is returned if the release was changed since it was read
+var ErrConflict = errors.New("release was changed concurrently")
+
+// ConflictError holds the current state of the releases, which didn't match the expected one
+type ConflictError struct {
+	Current []Record
+}
+
+func (e *ConflictError) Error() string {
+	return ErrConflict.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// ETag returns the quoted entity tag of the releases, which changes with any of their revisions
+func ETag(records []Record) string {
+	revisions := make([]string, 0, len(records))
+	for i := range records {
+		revisions = append(revisions, fmt.Sprintf("%s:%d", records[i].Key(), records[i].Revision))
+	}
+	sort.Strings(revisions)
+
+	sum := sha256.Sum256([]byte(strings.Join(revisions, "\n")))
+	return fmt.Sprintf("%q", fmt.Sprintf("%x", sum[:8]))
+}
+
+// matchETag reports if the ETag is one of the expected ones, the empty list matches any ETag
+func matchETag(etag string, ifMatch []string) bool {
+	if len(ifMatch) == 0 {
+		return true
+	}
+	for _, expected := range ifMatch {
+		if expected == anyETag || expected == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package release
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ImportMode describes how Import resolves the conflicts with the existing records
+type ImportMode string
+
+// ImportMode values
+const (
+	// ImportMerge keeps the record with the latest timestamp, the existing one wins the ties
+	ImportMerge ImportMode = "merge"
+	// ImportOverwrite replaces the existing records
+	ImportOverwrite ImportMode = "overwrite"
+	// ImportSkip keeps the existing records and only adds the missing ones
+	ImportSkip ImportMode = "skip"
+)
+
+// maxLineSize limits the size of the single exported record
+const maxLineSize = 1 << 20
+
+// ParseImportMode returns the ImportMode by its name
+func ParseImportMode(s string) (ImportMode, error) {
+	switch mode := ImportMode(s); mode {
+	case ImportMerge, ImportOverwrite, ImportSkip:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown import mode '%s'", s)
+}
+
+// ImportResult holds the number of the processed records by Import
+type ImportResult struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// exportedRecord is the line of the newline-delimited JSON export.
+// The platform is stored explicitly, as there is no key to hold it
+type exportedRecord struct {
+	Key      string `json:"key"`
+	Platform string `json:"platform"`
+	Record
+}
+
+// validate checks the record before it's imported
+func (e *exportedRecord) validate() error {
+	p, err := gapps.PlatformString(e.Platform)
+	if err != nil {
+		return fmt.Errorf("bad platform '%s'", e.Platform)
+	}
+	if _, err = time.Parse(models.DateOnlyFormat, e.Date); err != nil {
+		return fmt.Errorf("bad date '%s'", e.Date)
+	}
+	e.Record.Platform = p
+	if e.Key != "" && e.Key != e.Record.Key() {
+		return fmt.Errorf("key '%s' doesn't match the platform and date", e.Key)
+	}
+	return nil
+}
+
+// Export writes every stored release as newline-delimited JSON, sorted by key.
+// It returns the number of the exported records
+func (r *Repository) Export(w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	count := 0
+	err := r.storage.View(func(tx db.Tx) error {
+		return tx.Scan("", "", false, func(key string, value []byte) (bool, error) {
+			record, err := decode(key, value)
+			if errors.Is(err, ErrBadKey) {
+				log.WithError(err).Warn("Skipping the key")
+				return true, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			if err = enc.Encode(exportedRecord{Key: key, Platform: record.Platform.String(), Record: *record}); err != nil {
+				return false, fmt.Errorf("unable to export record for key '%s': %w", key, err)
+			}
+			count++
+			return true, nil
+		})
+	})
+	if err != nil {
+		return count, err
+	}
+	if err = bw.Flush(); err != nil {
+		return count, fmt.Errorf("unable to export records: %w", err)
+	}
+	return count, nil
+}
+
+// Import loads the newline-delimited JSON written by Export inside of a single transaction.
+// Every record is validated first, nothing is saved if any of them is invalid
+func (r *Repository) Import(rd io.Reader, mode ImportMode, hooks ...CommitHook) (*ImportResult, error) {
+	if _, err := ParseImportMode(string(mode)); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(nil, maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e exportedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("unable to parse line %d: %w", line, err)
+		}
+		if err := e.validate(); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		e.Record.Source = SourceImport
+		records = append(records, e.Record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read records: %w", err)
+	}
+
+	var result *ImportResult
+	err := r.storage.Update(func(tx db.Tx) error {
+		result = &ImportResult{}
+		var changes []Change
+		for i := range records {
+			key := records[i].Key()
+			value, err := tx.Get(key)
+			exists := err == nil
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				return fmt.Errorf("unable to get value for key '%s' from DB: %w", key, err)
+			}
+			var existing *Record
+			records[i].Revision = 1
+			if exists {
+				if existing, err = decode(key, value); err != nil {
+					return err
+				}
+				records[i].Revision = existing.Revision + 1
+				if mode == ImportSkip || (mode == ImportMerge && records[i].Timestamp <= existing.Timestamp) {
+					result.Skipped++
+					continue
+				}
+			}
+
+			data, err := encode(&records[i])
+			if err != nil {
+				return err
+			}
+			if err = tx.Put(key, data); err != nil {
+				return fmt.Errorf("unable to put value for key '%s' to DB: %w", key, err)
+			}
+			if exists {
+				result.Updated++
+			} else {
+				result.Added++
+			}
+			changes = append(changes, Change{Key: key, Source: SourceImport, Before: existing, After: &records[i]})
+		}
+		return commit(tx, changes, hooks)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
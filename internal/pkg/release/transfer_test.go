@@ -0,0 +1,87 @@
+package release_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	source := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, true),
+		newTestRecord("20200102", gapps.PlatformX86_64, false),
+	)
+	var buf bytes.Buffer
+	count, err := source.Export(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	target := newTestRepository(t)
+	result, err := target.Import(&buf, release.ImportOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, &release.ImportResult{Added: 2}, result)
+
+	record, err := target.GetRelease("20200101", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, record.Disabled)
+	_, err = target.GetRelease("20200102", gapps.PlatformX86_64)
+	require.NoError(t, err)
+}
+
+func TestImportModes(t *testing.T) {
+	const data = `{"key":"arm/20200101","platform":"arm","date":"20200101","disabled":true,"ts":2}
+{"platform":"arm64","date":"20200101","ts":1}
+`
+	cases := []struct {
+		mode     release.ImportMode
+		ts       int64
+		want     release.ImportResult
+		disabled bool
+	}{
+		{release.ImportOverwrite, 3, release.ImportResult{Added: 1, Updated: 1}, true},
+		{release.ImportSkip, 1, release.ImportResult{Added: 1, Skipped: 1}, false},
+		{release.ImportMerge, 1, release.ImportResult{Added: 1, Updated: 1}, true},
+		{release.ImportMerge, 2, release.ImportResult{Added: 1, Skipped: 1}, false},
+	}
+	for _, c := range cases {
+		existing := newTestRecord("20200101", gapps.PlatformArm, false)
+		existing.Timestamp = c.ts
+		repo := newTestRepository(t, existing)
+
+		result, err := repo.Import(strings.NewReader(data), c.mode)
+		require.NoError(t, err, c.mode)
+		assert.Equal(t, &c.want, result, c.mode)
+		record, err := repo.GetRelease("20200101", gapps.PlatformArm)
+		require.NoError(t, err, c.mode)
+		assert.Equal(t, c.disabled, record.Disabled, c.mode)
+	}
+}
+
+func TestImportValidation(t *testing.T) {
+	for _, data := range []string{
+		`{"platform":"mips","date":"20200101"}`,
+		`{"date":"20200101"}`,
+		`{"platform":"arm","date":"2020-01-01"}`,
+		`{"key":"arm64/20200101","platform":"arm","date":"20200101"}`,
+		`not json`,
+	} {
+		repo := newTestRepository(t)
+		_, err := repo.Import(strings.NewReader(`{"platform":"arm","date":"20200102"}`+"\n"+data), release.ImportOverwrite)
+		assert.Error(t, err, data)
+
+		// nothing is saved
+		records, err := repo.ListReleases(release.Filter{IncludeDisabled: true})
+		require.NoError(t, err)
+		assert.Empty(t, records, data)
+	}
+
+	_, err := newTestRepository(t).Import(strings.NewReader(""), "replace")
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+// ValidatorsBucket holds the cache validators of the release sources, keyed by the source and the platform
+const ValidatorsBucket = "validators"
+
+// Validators hold the cache validators of the last response of the release source.
+// They are sent back with the conditional request, so the source can tell that nothing changed
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Date is the date of the release saved from the response, it's empty if nothing was saved.
+	// The validators are dropped with the release, so the source is asked for it again
+	Date string `json:"date,omitempty"`
+}
+
+// Empty reports if there are no validators
+func (v *Validators) Empty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// Validators returns the cache validators saved for the source of the platform, they are empty if there are none
+func (r *Repository) Validators(source string, p gapps.Platform) (*Validators, error) {
+	key := validatorsKey(source, p)
+	v := &Validators{}
+	err := r.storage.View(func(tx db.Tx) error {
+		b, err := tx.Bucket(ValidatorsBucket)
+		if err != nil {
+			return err
+		}
+		data, err := b.Get(key)
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to get validators '%s': %w", key, err)
+		}
+		if err = json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("unable to parse validators '%s': %w", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SaveValidators stores the cache validators for the source of the platform, the empty ones are removed
+func (r *Repository) SaveValidators(source string, p gapps.Platform, v *Validators) error {
+	key := validatorsKey(source, p)
+	return r.storage.Update(func(tx db.Tx) error {
+		b, err := tx.Bucket(ValidatorsBucket)
+		if err != nil {
+			return err
+		}
+		if v == nil || v.Empty() {
+			if err = b.Delete(key); err != nil && !errors.Is(err, db.ErrNotFound) {
+				return fmt.Errorf("unable to delete validators '%s': %w", key, err)
+			}
+			return nil
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("unable to encode validators '%s': %w", key, err)
+		}
+		if err = b.Put(key, data); err != nil {
+			return fmt.Errorf("unable to put validators '%s': %w", key, err)
+		}
+		return nil
+	})
+}
+
+// dropValidators removes the validators of the releases with the keys from all of the sources
+func dropValidators(tx db.Tx, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	b, err := tx.Bucket(ValidatorsBucket)
+	if err != nil {
+		return fmt.Errorf("unable to open validators bucket: %w", err)
+	}
+	releases := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		releases[key] = true
+	}
+
+	var dropped []string
+	err = b.Scan("", "", false, func(k string, value []byte) (bool, error) {
+		i := strings.LastIndexByte(k, keySeparator)
+		if i < 0 {
+			return true, nil
+		}
+		p, err := gapps.PlatformString(k[i+1:])
+		if err != nil {
+			return true, nil
+		}
+		var v Validators
+		if err = json.Unmarshal(value, &v); err != nil {
+			return false, fmt.Errorf("unable to parse validators '%s': %w", k, err)
+		}
+		if v.Date != "" && releases[Key(v.Date, p)] {
+			dropped = append(dropped, k)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	// the keys are removed after the scan, as the cursors don't allow changes while iterating
+	for _, k := range dropped {
+		if err = b.Delete(k); err != nil {
+			return fmt.Errorf("unable to delete validators '%s': %w", k, err)
+		}
+	}
+	return nil
+}
+
+func validatorsKey(source string, p gapps.Platform) string {
+	return source + string(keySeparator) + p.String()
+}
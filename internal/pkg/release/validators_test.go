@@ -0,0 +1,78 @@
+package release_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidators(t *testing.T) {
+	repo := newTestRepository(t)
+	v, err := repo.Validators("http", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, v.Empty())
+
+	saved := &release.Validators{ETag: `"abc"`, LastModified: "Sat, 01 Feb 2020 00:00:00 GMT"}
+	require.NoError(t, repo.SaveValidators("http", gapps.PlatformArm, saved))
+	v, err = repo.Validators("http", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.Equal(t, saved, v)
+
+	v, err = repo.Validators("http", gapps.PlatformArm64)
+	require.NoError(t, err)
+	assert.True(t, v.Empty())
+
+	// the validators of the other source are not used
+	v, err = repo.Validators("github", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, v.Empty())
+
+	// the validators are not the releases
+	records, err := repo.ListReleases(release.Filter{IncludeDisabled: true})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	require.NoError(t, repo.SaveValidators("http", gapps.PlatformArm, &release.Validators{}))
+	v, err = repo.Validators("http", gapps.PlatformArm)
+	require.NoError(t, err)
+	assert.True(t, v.Empty())
+}
+
+func TestDropValidators(t *testing.T) {
+	repo := newTestRepository(t,
+		newTestRecord("20200101", gapps.PlatformArm, false),
+		newTestRecord("20200102", gapps.PlatformArm, false),
+		newTestRecord("20200102", gapps.PlatformArm64, false),
+	)
+	for _, source := range []string{"http", "github"} {
+		for _, p := range []gapps.Platform{gapps.PlatformArm, gapps.PlatformArm64} {
+			require.NoError(t, repo.SaveValidators(source, p, &release.Validators{ETag: `"abc"`, Date: "20200102"}))
+		}
+	}
+	validators := func(p gapps.Platform) []bool {
+		var found []bool
+		for _, source := range []string{"http", "github"} {
+			v, err := repo.Validators(source, p)
+			require.NoError(t, err)
+			found = append(found, !v.Empty())
+		}
+		return found
+	}
+
+	// the removal of the other release keeps them
+	_, err := repo.ApplyRetention(release.Policy{KeepLast: 1}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true}, validators(gapps.PlatformArm))
+
+	// the release replaced by the import drops them
+	line := `{"platform":"arm","date":"20200102","ts":1}`
+	_, err = repo.Import(strings.NewReader(line), release.ImportOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, false}, validators(gapps.PlatformArm))
+	assert.Equal(t, []bool{true, true}, validators(gapps.PlatformArm64))
+}
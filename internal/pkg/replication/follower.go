@@ -0,0 +1,174 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	authHeader = "Authorization"
+	authFormat = "Bearer %s"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// FollowerStats describes the replication state of the Follower
+type FollowerStats struct {
+	// LeaderSeq is the sequence number of the last change applied from the leader
+	LeaderSeq uint64    `json:"leader_seq"`
+	LastSync  time.Time `json:"last_sync,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Applied   uint64    `json:"applied"`
+}
+
+// Follower pulls the change log of the leader and applies it to the local storage
+type Follower struct {
+	leader   *url.URL
+	key      string
+	client   *http.Client
+	releases *release.Repository
+
+	// syncMtx serializes the syncs, mtx guards the stats
+	syncMtx sync.Mutex
+	mtx     sync.RWMutex
+	stats   FollowerStats
+}
+
+// NewFollower creates new instance of Follower
+func NewFollower(opts ...Option) (*Follower, error) {
+	f := &Follower{client: &http.Client{Timeout: defaultTimeout}}
+	for _, opt := range opts {
+		if err := opt(f); err != nil {
+			return nil, fmt.Errorf("unable to create follower: %w", err)
+		}
+	}
+	if f.leader == nil {
+		return nil, errors.New("leader is not set")
+	}
+	if f.releases == nil {
+		return nil, errors.New("repository is nil")
+	}
+	return f, nil
+}
+
+// Sync applies the changes of the leader until there are no more, returning their number
+func (f *Follower) Sync(ctx context.Context) (int, error) {
+	f.syncMtx.Lock()
+	defer f.syncMtx.Unlock()
+
+	applied, err := f.sync(ctx)
+	seq, seqErr := f.releases.LeaderSeq()
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.stats.Applied += uint64(applied)
+	if seqErr == nil {
+		f.stats.LeaderSeq = seq
+	}
+	f.stats.LastError = ""
+	if err != nil {
+		f.stats.LastError = err.Error()
+		return applied, err
+	}
+	f.stats.LastSync = time.Now()
+	return applied, nil
+}
+
+func (f *Follower) sync(ctx context.Context) (int, error) {
+	applied := 0
+	for {
+		seq, err := f.releases.LeaderSeq()
+		if err != nil {
+			return applied, err
+		}
+
+		feed, err := f.fetch(ctx, seq)
+		if err != nil {
+			return applied, err
+		}
+		if err = f.releases.ApplyFeed(feed); err != nil {
+			return applied, fmt.Errorf("unable to apply the changes of the leader: %w", err)
+		}
+		applied += len(feed.Changes)
+
+		// the leader returns less than the limit only at the end of the log
+		if feed.Full || len(feed.Changes) < defaultLimit {
+			return applied, nil
+		}
+	}
+}
+
+// fetch requests the changes since the sequence number from the leader
+func (f *Follower) fetch(ctx context.Context, seq uint64) (*release.ChangeFeed, error) {
+	u := *f.leader
+	query := u.Query()
+	query.Set(queryArgSince, strconv.FormatUint(seq, 10))
+	query.Set(queryArgLimit, strconv.Itoa(defaultLimit))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request to the leader: %w", err)
+	}
+	if f.key != "" {
+		req.Header.Set(authHeader, fmt.Sprintf(authFormat, f.key))
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request the leader: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := &changesResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("unable to decode the leader response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.ChangeFeed == nil {
+		return nil, fmt.Errorf("leader responded with status %d: %s", resp.StatusCode, body.Error)
+	}
+	return body.ChangeFeed, nil
+}
+
+// Watch syncs with the leader with the interval until the context is canceled
+func (f *Follower) Watch(ctx context.Context, interval time.Duration) {
+	sync := func() {
+		applied, err := f.Sync(ctx)
+		if err != nil {
+			log.WithError(err).Error("Unable to sync with the leader")
+		}
+		if applied > 0 {
+			log.WithField("changes", applied).Info("Applied the changes of the leader")
+		}
+	}
+
+	sync()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Context canceled, exiting replication follower")
+			return
+		case <-ticker.C:
+			sync()
+		}
+	}
+}
+
+// Stats returns the replication state
+func (f *Follower) Stats() FollowerStats {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	return f.stats
+}
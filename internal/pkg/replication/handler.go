@@ -0,0 +1,76 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Query args of the changes endpoint
+const (
+	queryArgSince = "since"
+	queryArgLimit = "limit"
+)
+
+const (
+	// defaultLimit is the number of the changes returned at once if it's not requested
+	defaultLimit = 100
+	// maxLimit is the max number of the changes returned at once
+	maxLimit = 1000
+)
+
+type changesResponse struct {
+	*release.ChangeFeed
+	Error string `json:"error,omitempty"`
+}
+
+// ToJSON forms JSON body from a struct, ignoring Marshal error
+func (r *changesResponse) ToJSON() []byte {
+	body, _ := json.Marshal(r)
+	return body
+}
+
+// NewHandler returns the changes endpoint of the leader, which serves the change log since the sequence number
+func NewHandler(releases *release.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &changesResponse{}
+		query := r.URL.Query()
+		since, limit := uint64(0), defaultLimit
+		var err error
+		if value := query.Get(queryArgSince); value != "" {
+			if since, err = strconv.ParseUint(value, 10, 64); err != nil {
+				resp.Error = fmt.Sprintf("unable to parse '%s' param: %s", queryArgSince, err)
+				respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+				return
+			}
+		}
+		if value := query.Get(queryArgLimit); value != "" {
+			if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxLimit {
+				resp.Error = fmt.Sprintf("unable to parse '%s' param: expected number from 1 to %d", queryArgLimit, maxLimit)
+				respondJSON(w, http.StatusBadRequest, resp.ToJSON())
+				return
+			}
+		}
+
+		if resp.ChangeFeed, err = releases.ChangesSince(since, limit); err != nil {
+			resp.Error = err.Error()
+			respondJSON(w, http.StatusInternalServerError, resp.ToJSON())
+			return
+		}
+		respondJSON(w, http.StatusOK, resp.ToJSON())
+	}
+}
+
+func respondJSON(w http.ResponseWriter, code int, body []byte) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	if _, err := w.Write(body); err != nil {
+		log.WithError(err).Error("Unable to write answer")
+	}
+}
@@ -0,0 +1,49 @@
+package replication
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+)
+
+// Option serves as the follower configuration
+type Option func(*Follower) error
+
+// WithLeader provides the changes endpoint URL of the leader and the auth key for it
+func WithLeader(endpoint, key string) Option {
+	return func(f *Follower) error {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return errors.New("leader URL must be absolute")
+		}
+		f.leader, f.key = u, key
+		return nil
+	}
+}
+
+// WithRepository provides release Repository to the follower
+func WithRepository(repo *release.Repository) Option {
+	return func(f *Follower) error {
+		if repo == nil {
+			return errors.New("repository is nil")
+		}
+		f.releases = repo
+		return nil
+	}
+}
+
+// WithHTTPClient provides the HTTP client for the requests to the leader
+func WithHTTPClient(client *http.Client) Option {
+	return func(f *Follower) error {
+		if client == nil {
+			return errors.New("HTTP client is nil")
+		}
+		f.client = client
+		return nil
+	}
+}
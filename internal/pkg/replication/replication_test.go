@@ -0,0 +1,106 @@
+package replication_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/internal/pkg/replication"
+	"github.com/opengapps/package-api/pkg/gapps"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "key"
+
+func newTestRepository(t *testing.T) *release.Repository {
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	repo, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	return repo
+}
+
+// newTestServer serves the changes of the repository with the same auth as the service
+func newTestServer(t *testing.T, repo *release.Repository) *httptest.Server {
+	handler := replication.NewHandler(repo)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReplication(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	ctx := context.Background()
+	leader := newTestRepository(t)
+	for _, p := range []gapps.Platform{gapps.PlatformArm, gapps.PlatformArm64} {
+		record := release.Record{ArchRecord: models.ArchRecord{Date: "20200101"}, Platform: p}
+		require.NoError(t, leader.SaveRelease(&record))
+	}
+
+	// the follower serves the changes too, so the followers can be chained
+	follower := newTestRepository(t)
+	first, err := replication.NewFollower(
+		replication.WithLeader(newTestServer(t, leader).URL, testKey),
+		replication.WithRepository(follower),
+	)
+	require.NoError(t, err)
+	chained := newTestRepository(t)
+	second, err := replication.NewFollower(
+		replication.WithLeader(newTestServer(t, follower).URL, testKey),
+		replication.WithRepository(chained),
+	)
+	require.NoError(t, err)
+
+	applied, err := first.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	_, err = second.Sync(ctx)
+	require.NoError(t, err)
+
+	_, err = leader.UpdateReleases(release.Filter{Platforms: []gapps.Platform{gapps.PlatformArm}}, func(record *release.Record) (bool, error) {
+		record.Disabled = true
+		record.Source = release.SourceAdmin
+		return true, nil
+	})
+	require.NoError(t, err)
+	applied, err = first.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, uint64(3), first.Stats().LeaderSeq)
+	_, err = second.Sync(ctx)
+	require.NoError(t, err)
+
+	for _, repo := range []*release.Repository{follower, chained} {
+		records, err := repo.ListReleases(release.Filter{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "arm64/20200101", records[0].Key())
+
+		record, err := repo.GetRelease("20200101", gapps.PlatformArm)
+		require.NoError(t, err)
+		assert.True(t, record.Disabled)
+		assert.Equal(t, release.SourceAdmin, record.Source)
+	}
+
+	unauthorized, err := replication.NewFollower(
+		replication.WithLeader(newTestServer(t, leader).URL, "bad"),
+		replication.WithRepository(newTestRepository(t)),
+	)
+	require.NoError(t, err)
+	_, err = unauthorized.Sync(ctx)
+	assert.Error(t, err)
+	assert.NotEmpty(t, unauthorized.Stats().LastError)
+}
@@ -2,37 +2,52 @@ package github
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"math/rand"
 	"sync"
 	"time"
 
-	"github.com/google/go-github/v47/github"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-	"golang.org/x/oauth2"
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/opengapps/package-api/pkg/gapps"
 
 	"github.com/opengapps/package-api/internal/pkg/config"
 	"github.com/opengapps/package-api/internal/pkg/db"
 	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
 )
 
 type client struct {
+	// ctx is the lifetime of the client, the shared checks are run with it
+	ctx     context.Context
 	cfg     *viper.Viper
-	client  *github.Client
-	storage Storage
+	sources map[gapps.Platform]ReleaseSource
+	// sourceTypes scope the validators of the platforms, so the validators of the other source are not sent
+	sourceTypes map[gapps.Platform]string
+	releases    *release.Repository
 
 	once sync.Once
+
+	mtx      sync.Mutex
+	failures map[gapps.Platform]int
+	// inflight holds the checks of the platforms in progress, keyed by the platform
+	inflight singleflight.Group
 }
 
-// NewClient creates new Github client
+// maxBackoff limits the delay between the attempts of the platform check
+const maxBackoff = time.Minute
+
+// NewClient creates new Github client, which watches the release sources selected by the config
 func NewClient(ctx context.Context, opts ...Option) (*client, error) {
-	c := &client{}
+	c := &client{
+		ctx:         ctx,
+		sources:     make(map[gapps.Platform]ReleaseSource),
+		sourceTypes: make(map[gapps.Platform]string),
+		failures:    make(map[gapps.Platform]int),
+	}
 	for _, opt := range opts {
 		if err := opt(c); err != nil {
 			return nil, fmt.Errorf("unable to create client: %w", err)
@@ -41,16 +56,21 @@ func NewClient(ctx context.Context, opts ...Option) (*client, error) {
 	if c.cfg == nil {
 		return nil, errors.New("config is nil")
 	}
-	if c.storage == nil {
-		return nil, errors.New("storage is nil")
+	if c.releases == nil {
+		return nil, errors.New("repository is nil")
 	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: c.cfg.GetString(config.GithubTokenKey)},
-	)
-	c.client = github.NewClient(oauth2.NewClient(ctx, ts))
-	if c.client == nil {
-		return nil, errors.New("client for Github is nil")
+	// the sources provided with the options take precedence over the config
+	for arch := range c.sources {
+		c.sourceTypes[arch] = SourceCustom
+	}
+	sources, types, err := newSources(ctx, c.cfg, c.sources)
+	if err != nil {
+		return nil, err
+	}
+	for arch, source := range sources {
+		c.sources[arch] = source
+		c.sourceTypes[arch] = types[arch]
 	}
 
 	return c, nil
@@ -60,11 +80,21 @@ func (c *client) Watch(ctx context.Context) {
 	c.once.Do(func() { c.watch(ctx) })
 }
 
+// Check saves the latest releases of the platforms once, all of them are checked if none are provided.
+// Every platform is checked independently
+func (c *client) Check(ctx context.Context, platforms ...gapps.Platform) *CheckSummary {
+	return c.checkRelease(ctx, platforms...)
+}
+
+// Trigger starts the check of the platforms in background and logs its summary.
+// It's stopped with the lifetime of the client
+func (c *client) Trigger(platforms ...gapps.Platform) {
+	go c.checkRelease(c.ctx, platforms...).Log()
+}
+
 // watch starts the release watcher
 func (c *client) watch(ctx context.Context) {
-	if err := c.checkRelease(ctx); err != nil {
-		log.WithError(err).Error("Unable to check for the latest release")
-	}
+	c.checkRelease(ctx).Log()
 
 	period := c.cfg.GetDuration(config.GithubWatchIntervalKey)
 	ticker := time.NewTicker(period)
@@ -76,77 +106,187 @@ func (c *client) watch(ctx context.Context) {
 			ticker.Stop()
 			return
 		case <-ticker.C:
-			if err := c.checkRelease(ctx); err != nil {
-				log.WithError(err).Error("Unable to check for the latest release")
-			}
+			c.checkRelease(ctx).Log()
 		}
 	}
 }
 
-func (c *client) checkRelease(ctx context.Context) error {
-	var resp models.ListResponse
+// checkRelease checks the platforms in parallel, the failure of one of them doesn't affect the others
+func (c *client) checkRelease(ctx context.Context, platforms ...gapps.Platform) *CheckSummary {
+	if len(platforms) == 0 {
+		platforms = gapps.PlatformValues()
+	}
+	summary := &CheckSummary{Results: make([]PlatformResult, len(platforms))}
+
+	var wg sync.WaitGroup
+	for i, arch := range platforms {
+		wg.Add(1)
+		go func(i int, arch gapps.Platform) {
+			defer wg.Done()
+			summary.Results[i] = c.sharedCheck(ctx, arch)
+		}(i, arch)
+	}
+	wg.Wait()
+
+	return summary
+}
+
+// sharedCheck joins the check of the platform in progress instead of starting the new one.
+// The check is run with the lifetime of the client, as the other callers may join it,
+// and the context only stops waiting for its result
+func (c *client) sharedCheck(ctx context.Context, arch gapps.Platform) PlatformResult {
+	ch := c.inflight.DoChan(arch.String(), func() (interface{}, error) {
+		return c.checkPlatform(c.ctx, arch), nil
+	})
+	select {
+	case res := <-ch:
+		result := res.Val.(PlatformResult)
+		result.Shared = res.Shared
+		return result
+	case <-ctx.Done():
+		return PlatformResult{Platform: arch, Status: StatusInProgress}
+	}
+}
+
+// checkPlatform checks the platform, retrying the failed attempts with the exponential backoff
+func (c *client) checkPlatform(ctx context.Context, arch gapps.Platform) PlatformResult {
+	result := PlatformResult{Platform: arch}
+	retries := c.cfg.GetInt(config.GithubRetriesKey)
+	backoff := c.cfg.GetDuration(config.GithubBackoffKey)
+
+	for {
+		result.Attempts++
+		err := c.syncPlatform(ctx, &result)
+		if err == nil {
+			c.resetFailures(arch)
+			return result
+		}
+		if result.Attempts > retries || ctx.Err() != nil {
+			log.WithError(err).WithFields(log.Fields{
+				"arch":                 arch,
+				"attempts":             result.Attempts,
+				"consecutive_failures": c.addFailure(arch),
+			}).Error("Unable to check for the latest release")
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			return result
+		}
+
+		delay := retryDelay(backoff, result.Attempts)
+		log.WithError(err).WithField("arch", arch).Warnf("Unable to check for the latest release, retrying in %s", delay)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
 
-	g, gCtx := errgroup.WithContext(ctx)
-	for _, arch := range gapps.PlatformValues() {
-		g.Go(c.addPackageFn(gCtx, &resp, arch))
+// syncPlatform fetches the LATEST file of the platform and saves its release if it's new
+func (c *client) syncPlatform(ctx context.Context, result *PlatformResult) error {
+	arch := result.Platform
+	sourceType := c.sourceTypes[arch]
+	validators, err := c.validators(sourceType, arch)
+	if err != nil {
+		return err
 	}
-	if err := g.Wait(); err != nil {
+	latest, err := c.sources[arch].LatestRelease(ctx, arch, validators)
+	if err != nil {
 		return err
 	}
+	if latest == nil {
+		result.Status = StatusUnchanged
+		return nil
+	}
 
-	// check results and save to DB if necessary
-	for arch, record := range resp.ArchList {
-		key := fmt.Sprintf(db.KeyTemplate, record.Date, arch)
-		_, err := c.storage.Get(key)
-
-		switch {
-		case err == nil:
-			// data is already there, continue
-			continue
-		case errors.Is(err, db.ErrNilValue), errors.Is(err, db.ErrNotFound):
-			// save the new data
-			var data []byte
-			dbRecord := db.Record{ArchRecord: record, Timestamp: time.Now().Unix()}
-			if data, err = json.Marshal(dbRecord); err != nil {
-				log.WithError(err).Errorf("Unable to marshal the data for the arch '%s' and date '%s'", arch, dbRecord.Date)
-				continue
-			}
-			if err = c.storage.Put(key, data); err != nil {
-				log.WithError(err).Errorf("Unable to save the data for the arch '%s' and date '%s'", arch, dbRecord.Date)
-			}
-		default:
-			return fmt.Errorf("unable to check DB key: %w", err)
+	record, err := latest.archRecord(arch)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		if err = c.saveRecord(arch, *record, result); err != nil {
+			return err
 		}
+		latest.validators.Date = record.Date
+	}
+	// the validators are saved after the release, so the failed save is retried on the next check
+	if err = c.releases.SaveValidators(sourceType, arch, &latest.validators); err != nil {
+		return err
+	}
+	result.Status = StatusSucceeded
+	result.Date = latest.Date
+	return nil
+}
+
+// validators returns the validators of the source for the platform.
+// They are trusted only while their release is stored, otherwise they are dropped, so the release is fetched again
+func (c *client) validators(sourceType string, arch gapps.Platform) (*release.Validators, error) {
+	v, err := c.releases.Validators(sourceType, arch)
+	if err != nil || v.Empty() || v.Date == "" {
+		return v, err
+	}
+	_, err = c.releases.GetRelease(v.Date, arch)
+	if err == nil {
+		return v, nil
 	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"arch": arch, "date": v.Date}).Warn("The release of the validators is gone, fetching it again")
+	if err = c.releases.SaveValidators(sourceType, arch, nil); err != nil {
+		return nil, err
+	}
+	return &release.Validators{}, nil
+}
 
+// saveRecord saves the release record if it's new, or merges the packages added to the stored one
+func (c *client) saveRecord(platform gapps.Platform, record models.ArchRecord, result *PlatformResult) error {
+	dbRecord := &release.Record{
+		ArchRecord: record,
+		Platform:   platform,
+		Source:     release.SourceWatcher,
+		Timestamp:  time.Now().Unix(),
+	}
+	merged, err := c.releases.MergeRelease(dbRecord, c.cfg.GetBool(config.GithubRemoveMissingKey))
+	if err != nil {
+		return fmt.Errorf("unable to save the data for the arch '%s' and date '%s': %w", platform, dbRecord.Date, err)
+	}
+
+	result.Saved = merged.Created
+	if merged.Diff != nil {
+		result.Diff = merged.Diff
+		log.WithFields(log.Fields{
+			"arch":    platform,
+			"date":    dbRecord.Date,
+			"added":   merged.Diff.Added,
+			"removed": merged.Diff.Removed,
+		}).Info("LATEST file of the stored release was changed")
+	}
 	return nil
 }
 
-func (c *client) addPackageFn(ctx context.Context, resp *models.ListResponse, arch gapps.Platform) func() error {
-	return func() error {
-		release, err := c.GetLatestRelease(ctx, arch)
-		if err != nil {
-			return err
-		}
+// addFailure counts the failed check of the platform, returning the number of the consecutive failures
+func (c *client) addFailure(arch gapps.Platform) int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.failures[arch]++
+	return c.failures[arch]
+}
 
-		for _, asset := range release.Assets {
-			for _, variant := range asset.Variants {
-				pkgAPI, err := gapps.AndroidString(strings.Replace(asset.API, ".", "", -1))
-				if err != nil {
-					return fmt.Errorf("unable to parse API '%s' in LATEST file for arch '%s': %w", asset.API, arch, err)
-				}
-
-				pkgVariant, err := gapps.VariantString(variant)
-				if err != nil {
-					return fmt.Errorf("unable to parse variant '%s' of API '%s' in LATEST file for arch '%s': %w", variant, asset.API, arch, err)
-				}
-
-				if err = resp.AddPackage(release.Date, arch, pkgAPI, pkgVariant); err != nil {
-					return fmt.Errorf("unable to add package for variant '%s' of API '%s' in LATEST file for arch '%s': %w", variant, asset.API, arch, err)
-				}
-			}
-		}
+// resetFailures clears the consecutive failures of the platform after the successful check
+func (c *client) resetFailures(arch gapps.Platform) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.failures, arch)
+}
 
-		return nil
+// retryDelay returns the exponential backoff of the attempt with the jitter, so the platforms don't retry together
+func retryDelay(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	d := backoff << (attempt - 1)
+	if d <= 0 || d > maxBackoff {
+		d = maxBackoff
 	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
 }
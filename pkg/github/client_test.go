@@ -0,0 +1,149 @@
+package github_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/db"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+	"github.com/opengapps/package-api/pkg/github"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	log.SetLevel(log.FatalLevel) // ignore watcher logging for tests
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "arm64"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "arm64", "LATEST.json"), []byte(testLatest), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "x86"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "x86", "LATEST.json"), []byte("{"), 0o644))
+
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	releases, err := release.NewRepository(storage)
+	require.NoError(t, err)
+
+	// the token is not needed without the Github source
+	cfg := viper.New()
+	cfg.Set(config.SourceTypeKey, github.SourceDir)
+	cfg.Set(config.SourceDirKey, dir)
+	c, err := github.NewClient(context.Background(), github.WithConfig(cfg), github.WithRepository(releases))
+	require.NoError(t, err)
+
+	// the broken platforms don't stop the others
+	summary := c.Check(context.Background())
+	assert.Equal(t, []string{"arm64"}, summary.Platforms(github.StatusSucceeded))
+	assert.Equal(t, []string{"arm", "x86", "x86_64"}, summary.Platforms(github.StatusFailed))
+	assert.Error(t, summary.Err())
+	record, err := releases.GetRelease("20200122", gapps.PlatformArm64)
+	require.NoError(t, err)
+	assert.Equal(t, release.SourceWatcher, record.Source)
+
+	summary = c.Check(context.Background(), gapps.PlatformArm64)
+	require.Len(t, summary.Results, 1)
+	assert.Equal(t, github.StatusUnchanged, summary.Results[0].Status)
+	assert.NoError(t, summary.Err())
+
+	// the late variant is merged into the stored release
+	late := strings.Replace(testLatest, `"pico"`, `"pico", "nano"`, 1)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "arm64", "LATEST.json"), []byte(late), 0o644))
+	summary = c.Check(context.Background(), gapps.PlatformArm64)
+	require.Len(t, summary.Results, 1)
+	assert.False(t, summary.Results[0].Saved)
+	require.NotNil(t, summary.Results[0].Diff)
+	assert.Equal(t, []string{"10.0/nano"}, summary.Results[0].Diff.Added)
+	record, err = releases.GetRelease("20200122", gapps.PlatformArm64)
+	require.NoError(t, err)
+	assert.Len(t, record.APIList["10.0"].VariantList, 2)
+
+	cfg.Set(config.SourceTypeKey, github.SourceGithub)
+	_, err = github.NewClient(context.Background(), github.WithConfig(cfg), github.WithRepository(releases))
+	assert.Error(t, err)
+}
+
+func TestCheckRemovedRelease(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "arm64"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "arm64", "LATEST.json"), []byte(testLatest), 0o644))
+
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	releases, err := release.NewRepository(storage)
+	require.NoError(t, err)
+	cfg := viper.New()
+	cfg.Set(config.SourceTypeKey, github.SourceDir)
+	cfg.Set(config.SourceDirKey, dir)
+	c, err := github.NewClient(context.Background(), github.WithConfig(cfg), github.WithRepository(releases))
+	require.NoError(t, err)
+
+	summary := c.Check(context.Background(), gapps.PlatformArm64)
+	require.Len(t, summary.Results, 1)
+	require.True(t, summary.Results[0].Saved)
+
+	// the release removed without the repository is fetched again instead of being unchanged
+	require.NoError(t, storage.Delete(release.Key("20200122", gapps.PlatformArm64)))
+	summary = c.Check(context.Background(), gapps.PlatformArm64)
+	require.Len(t, summary.Results, 1)
+	assert.Equal(t, github.StatusSucceeded, summary.Results[0].Status)
+	assert.True(t, summary.Results[0].Saved)
+	_, err = releases.GetRelease("20200122", gapps.PlatformArm64)
+	require.NoError(t, err)
+
+	summary = c.Check(context.Background(), gapps.PlatformArm64)
+	require.Len(t, summary.Results, 1)
+	assert.Equal(t, github.StatusUnchanged, summary.Results[0].Status)
+}
+
+// blockingSource reports the context error of the check once it's unblocked
+type blockingSource struct {
+	started chan struct{}
+	unblock chan struct{}
+	errs    chan error
+}
+
+func (s *blockingSource) LatestRelease(ctx context.Context, _ gapps.Platform, _ *release.Validators) (*github.LatestRelease, error) {
+	close(s.started)
+	<-s.unblock
+	s.errs <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+func TestSharedCheck(t *testing.T) {
+	log.SetLevel(log.FatalLevel)
+	storage := db.NewMemory()
+	t.Cleanup(func() { storage.Close(true) })
+	releases, err := release.NewRepository(storage)
+	require.NoError(t, err)
+
+	source := &blockingSource{started: make(chan struct{}), unblock: make(chan struct{}), errs: make(chan error, 1)}
+	cfg := viper.New()
+	cfg.Set(config.SourceTypeKey, github.SourceDir)
+	cfg.Set(config.SourceDirKey, t.TempDir())
+	c, err := github.NewClient(context.Background(),
+		github.WithConfig(cfg), github.WithRepository(releases), github.WithSource(gapps.PlatformArm64, source))
+	require.NoError(t, err)
+
+	// the caller stops waiting, while the check goes on for the others
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan *github.CheckSummary)
+	go func() { done <- c.Check(ctx, gapps.PlatformArm64) }()
+	<-source.started
+	cancel()
+	summary := <-done
+	require.Len(t, summary.Results, 1)
+	assert.Equal(t, github.StatusInProgress, summary.Results[0].Status)
+	assert.NoError(t, summary.Err())
+
+	close(source.unblock)
+	assert.NoError(t, <-source.errs, "the check must not be canceled by the caller")
+}
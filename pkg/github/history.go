@@ -0,0 +1,210 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v47/github"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+const (
+	// repositoryOwner owns the repositories of the platforms, their names are the platform names
+	repositoryOwner = "opengapps"
+	// repositoryBranch holds the LATEST files
+	repositoryBranch = "master"
+	// commitReleaseURLTemplate is the URL of the LATEST file at the commit of the platform repository
+	commitReleaseURLTemplate = "https://raw.githubusercontent.com/opengapps/%s/%s/LATEST.json"
+	commitsPerPage           = 100
+)
+
+// Version is the LATEST file of the platform changed by the commit
+type Version struct {
+	Commit  string
+	Time    time.Time
+	Release *LatestRelease
+}
+
+// ReleaseHistory provides the past versions of the LATEST files of the platforms
+type ReleaseHistory interface {
+	// History calls the function for every version of the LATEST file of the platform, the newest first
+	History(ctx context.Context, arch gapps.Platform, fn func(v *Version) error) error
+}
+
+// githubHistory walks the commits of the platform repositories with the Github API
+type githubHistory struct {
+	client *github.Client
+}
+
+// NewGithubHistory creates the ReleaseHistory of the opengapps repositories, the token is mandatory
+func NewGithubHistory(ctx context.Context, token string) (ReleaseHistory, error) {
+	if token == "" {
+		return nil, fmt.Errorf("missing mandatory key '%s'", config.GithubTokenKey)
+	}
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	if client == nil {
+		return nil, errors.New("client for Github is nil")
+	}
+	return &githubHistory{client: client}, nil
+}
+
+// History lists the commits of the LATEST file with the API, and fetches the file of every commit without it
+func (h *githubHistory) History(ctx context.Context, arch gapps.Platform, fn func(v *Version) error) error {
+	opts := &github.CommitsListOptions{
+		SHA:         repositoryBranch,
+		Path:        latestFileName,
+		ListOptions: github.ListOptions{PerPage: commitsPerPage},
+	}
+	for {
+		commits, resp, err := h.client.Repositories.ListCommits(ctx, repositoryOwner, arch.String(), opts)
+		if err != nil {
+			return fmt.Errorf("unable to list commits of LATEST file for arch '%s': %w", arch, err)
+		}
+		for _, commit := range commits {
+			v := &Version{Commit: commit.GetSHA(), Time: commit.GetCommit().GetCommitter().GetDate()}
+			if v.Release, err = h.release(ctx, arch, v.Commit); err != nil {
+				return err
+			}
+			if err = fn(v); err != nil {
+				return err
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// release returns the LATEST file of the commit, it's nil if the file can't be decoded
+func (h *githubHistory) release(ctx context.Context, arch gapps.Platform, commit string) (*LatestRelease, error) {
+	req, err := newLatestRequest(ctx, fmt.Sprintf(commitReleaseURLTemplate, arch, commit), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for the LATEST file for arch '%s': %w", arch, err)
+	}
+	resp, err := h.client.BareDo(ctx, req)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// the file is removed by the commit
+		log.WithField("commit", commit).Warn("Skipping the missing LATEST file")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire LATEST file of commit '%s' for arch '%s': %w", commit, arch, err)
+	}
+	defer resp.Body.Close()
+
+	latest, err := readLatest(resp.Response, arch)
+	if err != nil {
+		log.WithError(err).WithField("commit", commit).Warn("Skipping the bad LATEST file")
+		return nil, nil
+	}
+	return latest, nil
+}
+
+// gitHistory walks the commits of the local clones of the platform repositories
+type gitHistory struct {
+	dir string
+}
+
+// NewGitHistory creates the ReleaseHistory of the local <dir>/<arch> clones of the platform repositories
+func NewGitHistory(dir string) (ReleaseHistory, error) {
+	if dir == "" {
+		return nil, errors.New("directory of the git clones is empty")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("unable to find git: %w", err)
+	}
+	return &gitHistory{dir: dir}, nil
+}
+
+// History lists the commits of the LATEST file with git log, and reads the file of every commit with git show
+func (h *gitHistory) History(ctx context.Context, arch gapps.Platform, fn func(v *Version) error) error {
+	repo := filepath.Join(h.dir, arch.String())
+	out, err := h.git(ctx, repo, "log", "--format=%H %ct", "HEAD", "--", latestFileName)
+	if err != nil {
+		return fmt.Errorf("unable to list commits of LATEST file for arch '%s': %w", arch, err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		commit, ts, _ := strings.Cut(line, " ")
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unable to parse time of commit '%s' for arch '%s': %w", commit, arch, err)
+		}
+		v := &Version{Commit: commit, Time: time.Unix(sec, 0).UTC()}
+
+		data, err := h.git(ctx, repo, "show", commit+":"+latestFileName)
+		if err != nil {
+			// the file is removed by the commit
+			log.WithError(err).WithField("commit", commit).Warn("Skipping the missing LATEST file")
+		} else if err = json.Unmarshal(data, &v.Release); err != nil {
+			log.WithError(err).WithField("commit", commit).Warn("Skipping the bad LATEST file")
+			v.Release = nil
+		}
+		if err = fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *gitHistory) git(ctx context.Context, repo string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// HistoryRecords returns the release records of the platform from its history, one for every date.
+// The last version of the date is used, as it holds the fixes of the earlier ones.
+// The versions which can't be parsed are skipped
+func HistoryRecords(ctx context.Context, h ReleaseHistory, arch gapps.Platform) ([]release.Record, error) {
+	var records []release.Record
+	dates := make(map[string]bool)
+	err := h.History(ctx, arch, func(v *Version) error {
+		if v.Release == nil || dates[v.Release.Date] {
+			return nil
+		}
+		record, err := v.Release.archRecord(arch)
+		if err != nil || record == nil {
+			log.WithError(err).WithField("commit", v.Commit).Warn("Skipping the LATEST file without the release")
+			return nil
+		}
+		dates[v.Release.Date] = true
+		records = append(records, release.Record{
+			ArchRecord: *record,
+			Platform:   arch,
+			Source:     release.SourceBackfill,
+			Timestamp:  v.Time.Unix(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,54 @@
+package github_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opengapps/package-api/pkg/gapps"
+	"github.com/opengapps/package-api/pkg/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "arm64")
+	require.NoError(t, os.Mkdir(repo, 0o755))
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.org"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	commit := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, "LATEST.json"), []byte(content), 0o644))
+		git("add", "LATEST.json")
+		git("commit", "-q", "-m", "update")
+	}
+	git("init", "-q")
+	commit(strings.Replace(testLatest, "20200122", "20200120", 1))
+	commit("{")
+	commit(testLatest)
+	// the fix of the same date
+	commit(strings.Replace(testLatest, `"pico"`, `"pico", "nano"`, 1))
+
+	history, err := github.NewGitHistory(dir)
+	require.NoError(t, err)
+	records, err := github.HistoryRecords(context.Background(), history, gapps.PlatformArm64)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "20200122", records[0].Date)
+	assert.Len(t, records[0].APIList["10.0"].VariantList, 2)
+	assert.Equal(t, "20200120", records[1].Date)
+	assert.NotZero(t, records[1].Timestamp)
+
+	_, err = github.HistoryRecords(context.Background(), history, gapps.PlatformArm)
+	assert.Error(t, err)
+}
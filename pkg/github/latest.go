@@ -2,26 +2,24 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/opengapps/package-api/internal/pkg/models"
+	"github.com/opengapps/package-api/internal/pkg/release"
 	"github.com/opengapps/package-api/pkg/gapps"
 )
 
-const latestReleaseURLTemplate = "https://raw.githubusercontent.com/opengapps/%s/master/LATEST.json"
-
-var releaseURLMap = map[gapps.Platform]string{
-	gapps.PlatformArm:    fmt.Sprintf(latestReleaseURLTemplate, gapps.PlatformArm),
-	gapps.PlatformArm64:  fmt.Sprintf(latestReleaseURLTemplate, gapps.PlatformArm64),
-	gapps.PlatformX86:    fmt.Sprintf(latestReleaseURLTemplate, gapps.PlatformX86),
-	gapps.PlatformX86_64: fmt.Sprintf(latestReleaseURLTemplate, gapps.PlatformX86_64),
-}
-
 // LatestRelease describes the latest gapps release
 type LatestRelease struct {
 	Arch   string         `json:"arch"`
 	Date   string         `json:"date"`
 	Assets []ReleaseAsset `json:"assets"`
+
+	// validators of the response, they are saved once the release is
+	validators release.Validators
 }
 
 // ReleaseAsset describes the gapps release for API and its available variants
@@ -30,22 +28,66 @@ type ReleaseAsset struct {
 	Variants []string `json:"variants"`
 }
 
-// GetLatestRelease returns the latest release info for the selected architecture
-func (c *client) GetLatestRelease(ctx context.Context, arch gapps.Platform) (*LatestRelease, error) {
-	req, err := c.client.NewRequest(http.MethodGet, releaseURLMap[arch], nil)
+// newLatestRequest creates the request of the LATEST file, it's conditional if the validators are provided
+func newLatestRequest(ctx context.Context, url string, v *release.Validators) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create request for the LATEST file for arch '%s': %w", arch, err)
+		return nil, err
 	}
-
-	var release LatestRelease
-	resp, err := c.client.Do(ctx, req, &release)
-	if err != nil {
-		return nil, fmt.Errorf("unable to acquire LATEST file for arch '%s': %w", arch, err)
+	if v != nil {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
 	}
+	return req, nil
+}
 
+// readLatest decodes the LATEST file from the response with its validators, nil is returned if it wasn't modified
+func readLatest(resp *http.Response, arch gapps.Platform) (*LatestRelease, error) {
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unable to acquire LATEST file for arch '%s': got response '%s'", arch, resp.Status)
 	}
 
-	return &release, nil
+	var latest LatestRelease
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return nil, fmt.Errorf("unable to decode LATEST file for arch '%s': %w", arch, err)
+	}
+	latest.validators.ETag = resp.Header.Get("ETag")
+	latest.validators.LastModified = resp.Header.Get("Last-Modified")
+
+	return &latest, nil
+}
+
+// archRecord converts the release to the record of the arch, it's nil if the release has no assets
+func (l *LatestRelease) archRecord(arch gapps.Platform) (*models.ArchRecord, error) {
+	var resp models.ListResponse
+	for _, asset := range l.Assets {
+		for _, variant := range asset.Variants {
+			pkgAPI, err := gapps.AndroidString(strings.Replace(asset.API, ".", "", -1))
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse API '%s' in LATEST file for arch '%s': %w", asset.API, arch, err)
+			}
+
+			pkgVariant, err := gapps.VariantString(variant)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse variant '%s' of API '%s' in LATEST file for arch '%s': %w", variant, asset.API, arch, err)
+			}
+
+			if err = resp.AddPackage(l.Date, arch, pkgAPI, pkgVariant); err != nil {
+				return nil, fmt.Errorf("unable to add package for variant '%s' of API '%s' in LATEST file for arch '%s': %w", variant, asset.API, arch, err)
+			}
+		}
+	}
+
+	record, ok := resp.ArchList[arch.String()]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
 }
@@ -4,6 +4,9 @@ import (
 	"errors"
 
 	"github.com/spf13/viper"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
 )
 
 // Option serves as the client configuration
@@ -20,13 +23,24 @@ func WithConfig(cfg *viper.Viper) Option {
 	}
 }
 
-// WithStorage provides Storage to the client
-func WithStorage(storage Storage) Option {
+// WithRepository provides release Repository to the client
+func WithRepository(repo *release.Repository) Option {
+	return func(c *client) error {
+		if repo == nil {
+			return errors.New("repository is nil")
+		}
+		c.releases = repo
+		return nil
+	}
+}
+
+// WithSource provides the ReleaseSource of the platform to the client, overriding the config
+func WithSource(arch gapps.Platform, source ReleaseSource) Option {
 	return func(c *client) error {
-		if storage == nil {
-			return errors.New("storage is nil")
+		if source == nil {
+			return errors.New("release source is nil")
 		}
-		c.storage = storage
+		c.sources[arch] = source
 		return nil
 	}
 }
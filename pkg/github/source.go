@@ -0,0 +1,205 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v47/github"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+
+	"github.com/opengapps/package-api/internal/pkg/config"
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+// ReleaseSource provides the LATEST files of the platforms
+type ReleaseSource interface {
+	// LatestRelease returns the latest release of the platform.
+	// It returns nil if the release wasn't modified since the validators were received
+	LatestRelease(ctx context.Context, arch gapps.Platform, v *release.Validators) (*LatestRelease, error)
+}
+
+// ReleaseSource types
+const (
+	// SourceGithub fetches the LATEST files of the opengapps repositories
+	SourceGithub = "github"
+	// SourceHTTP fetches the LATEST files by the URL template
+	SourceHTTP = "http"
+	// SourceDir reads the LATEST files from the local directory
+	SourceDir = "dir"
+	// SourceCustom is the type of the sources provided with WithSource
+	SourceCustom = "custom"
+)
+
+const (
+	latestReleaseURLTemplate = "https://raw.githubusercontent.com/opengapps/%s/master/LATEST.json"
+	// archPlaceholder is replaced with the platform name in the URL template
+	archPlaceholder = "{arch}"
+	// latestFileName is the name of the LATEST file in the platform directory
+	latestFileName = "LATEST.json"
+)
+
+// githubSource fetches the LATEST files of the opengapps repositories with the Github client
+type githubSource struct {
+	client *github.Client
+}
+
+// NewGithubSource creates the ReleaseSource of the opengapps repositories, the token is mandatory
+func NewGithubSource(ctx context.Context, token string) (ReleaseSource, error) {
+	if token == "" {
+		return nil, fmt.Errorf("missing mandatory key '%s'", config.GithubTokenKey)
+	}
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	if client == nil {
+		return nil, errors.New("client for Github is nil")
+	}
+	return &githubSource{client: client}, nil
+}
+
+// LatestRelease returns the latest release of the platform from its repository
+func (s *githubSource) LatestRelease(ctx context.Context, arch gapps.Platform, v *release.Validators) (*LatestRelease, error) {
+	req, err := newLatestRequest(ctx, fmt.Sprintf(latestReleaseURLTemplate, arch), v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for the LATEST file for arch '%s': %w", arch, err)
+	}
+
+	// the Github client reports 304 as the error
+	resp, err := s.client.BareDo(ctx, req)
+	if resp != nil && resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire LATEST file for arch '%s': %w", arch, err)
+	}
+	defer resp.Body.Close()
+	return readLatest(resp.Response, arch)
+}
+
+// httpSource fetches the LATEST files by the URL template
+type httpSource struct {
+	client   *http.Client
+	template string
+}
+
+// NewHTTPSource creates the ReleaseSource fetching the URL template with the platform name in place of {arch}
+func NewHTTPSource(client *http.Client, template string) (ReleaseSource, error) {
+	if client == nil {
+		return nil, errors.New("HTTP client is nil")
+	}
+	if template == "" {
+		return nil, fmt.Errorf("missing mandatory key '%s'", config.SourceURLKey)
+	}
+	return &httpSource{client: client, template: template}, nil
+}
+
+// LatestRelease returns the latest release of the platform from its URL
+func (s *httpSource) LatestRelease(ctx context.Context, arch gapps.Platform, v *release.Validators) (*LatestRelease, error) {
+	url := strings.ReplaceAll(s.template, archPlaceholder, arch.String())
+	req, err := newLatestRequest(ctx, url, v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for the LATEST file for arch '%s': %w", arch, err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire LATEST file for arch '%s': %w", arch, err)
+	}
+	defer resp.Body.Close()
+	return readLatest(resp, arch)
+}
+
+// dirSource reads the LATEST files from the platform directories, like the checkouts of the opengapps repositories
+type dirSource struct {
+	dir string
+}
+
+// NewDirSource creates the ReleaseSource reading the <dir>/<arch>/LATEST.json files
+func NewDirSource(dir string) (ReleaseSource, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("missing mandatory key '%s'", config.SourceDirKey)
+	}
+	return &dirSource{dir: dir}, nil
+}
+
+// LatestRelease returns the latest release of the platform from its file.
+// The file is read only if its size or modification time was changed
+func (s *dirSource) LatestRelease(_ context.Context, arch gapps.Platform, v *release.Validators) (*LatestRelease, error) {
+	path := filepath.Join(s.dir, arch.String(), latestFileName)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire LATEST file for arch '%s': %w", arch, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("unable to acquire LATEST file for arch '%s': %w", arch, err)
+	}
+	etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+	if v != nil && v.ETag == etag {
+		return nil, nil
+	}
+
+	var latest LatestRelease
+	if err = json.NewDecoder(f).Decode(&latest); err != nil {
+		return nil, fmt.Errorf("unable to decode LATEST file for arch '%s': %w", arch, err)
+	}
+	latest.validators.ETag = etag
+	return &latest, nil
+}
+
+// newSources creates the release sources selected by the config for the platforms without the provided ones.
+// It returns the types of the sources too
+func newSources(ctx context.Context, cfg *viper.Viper, provided map[gapps.Platform]ReleaseSource) (map[gapps.Platform]ReleaseSource, map[gapps.Platform]string, error) {
+	types := make(map[gapps.Platform]string)
+	for _, arch := range gapps.PlatformValues() {
+		types[arch] = cfg.GetString(config.SourceTypeKey)
+	}
+	for name, sourceType := range cfg.GetStringMapString(config.SourcePlatformsKey) {
+		arch, err := gapps.PlatformString(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse arch '%s' of the release source: %w", name, err)
+		}
+		types[arch] = sourceType
+	}
+	// e.g. the token is not needed if none of the platforms use Github
+	for arch := range provided {
+		delete(types, arch)
+	}
+
+	// the sources of the same type are shared by the platforms
+	shared := make(map[string]ReleaseSource)
+	sources := make(map[gapps.Platform]ReleaseSource)
+	for arch, sourceType := range types {
+		source, ok := shared[sourceType]
+		if !ok {
+			var err error
+			switch sourceType {
+			case SourceGithub:
+				source, err = NewGithubSource(ctx, cfg.GetString(config.GithubTokenKey))
+			case SourceHTTP:
+				source, err = NewHTTPSource(&http.Client{Timeout: cfg.GetDuration(config.HTTPTimeoutKey)}, cfg.GetString(config.SourceURLKey))
+			case SourceDir:
+				source, err = NewDirSource(cfg.GetString(config.SourceDirKey))
+			default:
+				err = fmt.Errorf("unknown release source '%s'", sourceType)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to create release source for arch '%s': %w", arch, err)
+			}
+			shared[sourceType] = source
+		}
+		sources[arch] = source
+	}
+	return sources, types, nil
+}
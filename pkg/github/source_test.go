@@ -0,0 +1,68 @@
+package github_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+	"github.com/opengapps/package-api/pkg/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLatest = `{"arch": "arm64", "date": "20200122", "assets": [{"api": "10.0", "variants": ["pico"]}]}`
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "arm64"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "arm64", "LATEST.json"), []byte(testLatest), 0o644))
+
+	source, err := github.NewDirSource(dir)
+	require.NoError(t, err)
+	latest, err := source.LatestRelease(context.Background(), gapps.PlatformArm64, nil)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, "20200122", latest.Date)
+	require.Len(t, latest.Assets, 1)
+	assert.Equal(t, "10.0", latest.Assets[0].API)
+
+	_, err = source.LatestRelease(context.Background(), gapps.PlatformArm, nil)
+	assert.Error(t, err)
+}
+
+func TestHTTPSource(t *testing.T) {
+	const etag = `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/arm64/LATEST.json" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(testLatest))
+	}))
+	t.Cleanup(server.Close)
+
+	source, err := github.NewHTTPSource(server.Client(), server.URL+"/{arch}/LATEST.json")
+	require.NoError(t, err)
+	latest, err := source.LatestRelease(context.Background(), gapps.PlatformArm64, &release.Validators{})
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, "20200122", latest.Date)
+
+	latest, err = source.LatestRelease(context.Background(), gapps.PlatformArm64, &release.Validators{ETag: etag})
+	require.NoError(t, err)
+	assert.Nil(t, latest)
+
+	_, err = source.LatestRelease(context.Background(), gapps.PlatformX86, nil)
+	assert.Error(t, err)
+}
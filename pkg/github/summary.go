@@ -0,0 +1,96 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/opengapps/package-api/internal/pkg/release"
+	"github.com/opengapps/package-api/pkg/gapps"
+)
+
+// Platform check statuses
+const (
+	// StatusSucceeded means the LATEST file was fetched and its release is stored
+	StatusSucceeded = "succeeded"
+	// StatusUnchanged means the LATEST file wasn't modified since the last check
+	StatusUnchanged = "unchanged"
+	// StatusFailed means the platform wasn't checked after all of the attempts
+	StatusFailed = "failed"
+	// StatusInProgress means the caller stopped waiting for the check, which still goes on in background
+	StatusInProgress = "in_progress"
+)
+
+// PlatformResult describes the check of the single platform
+type PlatformResult struct {
+	Platform gapps.Platform `json:"platform"`
+	Status   string         `json:"status"`
+	// Date is the date of the release in the LATEST file, it's empty if the file wasn't fetched
+	Date string `json:"date,omitempty"`
+	// Saved reports if the release is new and it was saved by the check
+	Saved bool `json:"saved,omitempty"`
+	// Diff lists the packages changed in the LATEST file of the stored release
+	Diff     *release.Diff `json:"diff,omitempty"`
+	Attempts int           `json:"attempts"`
+	Error    string        `json:"error,omitempty"`
+	// Shared reports if the result belongs to the check which was already in progress
+	Shared bool `json:"shared,omitempty"`
+}
+
+// CheckSummary holds the results of the check cycle, one for every platform
+type CheckSummary struct {
+	Results []PlatformResult `json:"results"`
+}
+
+// Platforms returns the platforms with the status
+func (s *CheckSummary) Platforms(status string) []string {
+	var platforms []string
+	for _, result := range s.Results {
+		if result.Status == status {
+			platforms = append(platforms, result.Platform.String())
+		}
+	}
+	return platforms
+}
+
+// Err returns the error describing the failed platforms, nil if there are none
+func (s *CheckSummary) Err() error {
+	var failed []string
+	for _, result := range s.Results {
+		if result.Status == StatusFailed {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.Platform, result.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unable to check platforms: %s", strings.Join(failed, "; "))
+}
+
+// Log prints the summary, the cycle without news is logged in debug mode only
+func (s *CheckSummary) Log() {
+	entry := log.WithFields(log.Fields{
+		"succeeded":   s.Platforms(StatusSucceeded),
+		"unchanged":   s.Platforms(StatusUnchanged),
+		"failed":      s.Platforms(StatusFailed),
+		"in_progress": s.Platforms(StatusInProgress),
+	})
+	var saved, changed []string
+	for _, result := range s.Results {
+		if result.Saved {
+			saved = append(saved, result.Platform.String())
+		}
+		if result.Diff != nil {
+			changed = append(changed, result.Platform.String())
+		}
+	}
+	switch {
+	case len(s.Platforms(StatusFailed)) > 0:
+		entry.Warn("Checked the latest releases with failures")
+	case len(saved) > 0 || len(changed) > 0:
+		entry.WithField("saved", saved).WithField("changed", changed).Info("Checked the latest releases")
+	default:
+		entry.Debug("Checked the latest releases")
+	}
+}